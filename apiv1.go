@@ -0,0 +1,440 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ------------------------------------------------------
+// 33. 버전 있는 JSON API (/api/v1)
+// ------------------------------------------------------
+// 기존 /console/api/* 는 콘솔 화면용(텍스트/HTML 응답)으로 그대로 두고,
+// 스크립트/외부 도구는 /api/v1 을 사용한다. 문서는 static/openapi.json
+
+const apiV1Prefix = "/api/v1"
+
+// 공개 OpenAPI 문서
+const openAPIFile = "static/openapi.json"
+
+// 오류 코드 (응답의 error.code, 한 번 정한 값은 바꾸지 않는다)
+const (
+    apiErrBadRequest   = "bad_request"
+    apiErrUnauthorized = "unauthorized"
+    apiErr2FARequired  = "2fa_enrollment_required"
+    apiErrForbidden    = "forbidden"
+    apiErrTokenScope   = "token_scope"
+    apiErrCSRF         = "csrf_failed"
+    apiErrNotFound     = "not_found"
+    apiErrConflict     = "conflict"
+    apiErrPrecondition = "precondition_required"
+    apiErrValidation   = "validation_failed"
+    apiErrProjectBusy  = "project_busy"
+    apiErrInternal     = "internal_error"
+)
+
+type apiErrorBody struct {
+    Code    string      `json:"code"`
+    Message string      `json:"message"`
+    Details interface{} `json:"details,omitempty"`
+}
+
+// apiError: {"error": {"code", "message", "details"}} 로 응답하고 중단
+func apiError(c *gin.Context, status int, code, message string, details ...interface{}) {
+    body := apiErrorBody{Code: code, Message: message}
+    if len(details) > 0 {
+        body.Details = details[0]
+    }
+    c.AbortWithStatusJSON(status, gin.H{"error": body})
+}
+
+// isAPIRequest: /api/ 아래 요청인지 (CSRF, 404 등을 JSON 으로 응답할지 판단)
+func isAPIRequest(c *gin.Context) bool {
+    return strings.HasPrefix(c.Request.URL.Path, "/api/")
+}
+
+// apiNotFound: 없는 경로. /api 아래는 JSON, 나머지는 gin 기본과 같은 텍스트
+func apiNotFound(c *gin.Context) {
+    if isAPIRequest(c) {
+        apiError(c, http.StatusNotFound, apiErrNotFound, "없는 API 경로입니다: "+c.Request.Method+" "+c.Request.URL.Path)
+        return
+    }
+    c.String(http.StatusNotFound, "404 page not found")
+}
+
+// apiAuthRequired: AuthRequired + require2FAEnrollment 의 JSON 버전 (리다이렉트하지 않음)
+func apiAuthRequired() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if raw := bearerToken(c); raw != "" {
+            t, err := authenticateToken(raw)
+            if err != nil {
+                apiError(c, http.StatusUnauthorized, apiErrUnauthorized, "API 토큰 인증 실패: "+err.Error())
+                return
+            }
+            c.Set(ctxAPIToken, t)
+        } else if !checkSession(c) {
+            apiError(c, http.StatusUnauthorized, apiErrUnauthorized, "로그인하거나 Authorization: Bearer 토큰을 보내야 합니다.")
+            return
+        }
+        if needs2FAEnrollment(currentUser(c)) {
+            apiError(c, http.StatusForbidden, apiErr2FARequired, "2단계 인증 등록이 필요합니다. /account/2fa 에서 등록하세요.")
+            return
+        }
+        c.Next()
+    }
+}
+
+// apiRequire: requirePermission 의 JSON 버전. 경로별 권한은 핸들러에서 apiTarget 으로 확인한다.
+// (JSON 본문의 경로는 미들웨어에서 읽지 않으므로)
+func apiRequire(perm string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if !tokenAllows(c, perm) {
+            apiError(c, http.StatusForbidden, apiErrTokenScope, "API 토큰의 권한 범위에 "+perm+" 가 없습니다.", gin.H{"permission": perm})
+            return
+        }
+        if !hasAnyPermission(currentUser(c), perm) {
+            apiError(c, http.StatusForbidden, apiErrForbidden, "이 기능을 사용할 권한이 없습니다. (필요 권한: "+perm+")", gin.H{"permission": perm})
+            return
+        }
+        c.Next()
+    }
+}
+
+// apiTarget: 요청한 경로를 실제 경로로 바꾸고 그 프로젝트에 대한 perm 권한을 확인 (실패하면 응답 후 ok=false)
+func apiTarget(c *gin.Context, name, rel, perm string) (string, bool) {
+    if rel == "" {
+        apiError(c, http.StatusBadRequest, apiErrBadRequest, name+" 필요", gin.H{"field": name})
+        return "", false
+    }
+    fullPath, err := resolvePath(rel)
+    if err != nil {
+        apiError(c, http.StatusBadRequest, apiErrBadRequest, err.Error(), gin.H{"field": name})
+        return "", false
+    }
+    if project := projectOf(fullPath); !hasProjectPermission(currentUser(c), project, perm) {
+        apiError(c, http.StatusForbidden, apiErrForbidden,
+            fmt.Sprintf("%s 프로젝트에 대한 권한이 없습니다. (필요 권한: %s)", project, perm),
+            gin.H{"project": project, "permission": perm})
+        return "", false
+    }
+    return fullPath, true
+}
+
+// bindAPIJSON: JSON 본문 읽기 (실패하면 400 응답 후 false)
+func bindAPIJSON(c *gin.Context, v interface{}) bool {
+    if err := c.ShouldBindJSON(v); err != nil {
+        apiError(c, http.StatusBadRequest, apiErrBadRequest, "JSON 본문을 읽을 수 없습니다: "+err.Error())
+        return false
+    }
+    return true
+}
+
+// apiJobAccepted: 백그라운드 작업을 202 로 응답 (Location 은 작업 조회 주소)
+func apiJobAccepted(c *gin.Context, j *job) {
+    c.Header("X-Job-ID", j.ID)
+    c.Header("Location", apiV1Prefix+"/jobs/"+j.ID)
+    c.JSON(http.StatusAccepted, gin.H{"job": j.view(false)})
+}
+
+// apiBusy: respondBusy 의 JSON 버전
+func apiBusy(c *gin.Context, busy *job) {
+    var details interface{}
+    if busy != nil {
+        c.Header("X-Job-ID", busy.ID)
+        details = gin.H{"job": busy.ID, "kind": busy.Kind}
+    }
+    apiError(c, http.StatusLocked, apiErrProjectBusy, "같은 프로젝트의 다른 작업이 진행 중입니다. 끝난 뒤 다시 시도하세요.", details)
+}
+
+// ------------------------------------------------------
+// 33-1. 디렉토리/파일
+// ------------------------------------------------------
+
+// GET /api/v1/directories
+func apiListDirectories(c *gin.Context) {
+    dirs, err := ioutil.ReadDir(baseDir)
+    if err != nil {
+        apiError(c, http.StatusInternalServerError, apiErrInternal, err.Error())
+        return
+    }
+    result := visibleDirectories(currentUser(c), dirs)
+    if result == nil {
+        result = []string{}
+    }
+    c.JSON(http.StatusOK, gin.H{"directories": result})
+}
+
+// GET /api/v1/files?dir=
+func apiListFiles(c *gin.Context) {
+    dir := c.Query("dir")
+    fullDir, ok := apiTarget(c, "dir", dir, permRead)
+    if !ok {
+        return
+    }
+    infos, err := ioutil.ReadDir(fullDir)
+    if err != nil {
+        apiError(c, http.StatusNotFound, apiErrNotFound, "디렉토리를 읽을 수 없습니다: "+dir)
+        return
+    }
+    files := []string{}
+    for _, f := range infos {
+        if !f.IsDir() {
+            files = append(files, filepath.Join(dir, f.Name()))
+        }
+    }
+    c.JSON(http.StatusOK, gin.H{"dir": dir, "files": files})
+}
+
+// GET /api/v1/file?path=
+func apiGetFile(c *gin.Context) {
+    p := c.Query("path")
+    fullPath, ok := apiTarget(c, "path", p, permRead)
+    if !ok {
+        return
+    }
+    data, err := ioutil.ReadFile(fullPath)
+    if err != nil {
+        apiError(c, http.StatusNotFound, apiErrNotFound, "파일을 읽을 수 없습니다: "+p)
+        return
+    }
+    etag := fileETag(data)
+    c.Header("ETag", etag)
+    c.JSON(http.StatusOK, gin.H{"path": p, "content": string(data), "etag": etag})
+}
+
+type apiSaveRequest struct {
+    Path    string `json:"path"`
+    Content string `json:"content"`
+    ETag    string `json:"etag"`    // GET /api/v1/file 의 etag (또는 If-Match 헤더)
+    Message string `json:"message"`
+    Force   bool   `json:"force"`   // 검증 건너뛰기
+}
+
+// PUT /api/v1/file: 저장만 한다 (적용은 POST /api/v1/restart)
+func apiSaveFile(c *gin.Context) {
+    var req apiSaveRequest
+    if !bindAPIJSON(c, &req) {
+        return
+    }
+    fullPath, ok := apiTarget(c, "path", req.Path, permEdit)
+    if !ok {
+        return
+    }
+    etag := req.ETag
+    if etag == "" {
+        etag = c.GetHeader("If-Match")
+    }
+    if etag == "" {
+        apiError(c, http.StatusPreconditionRequired, apiErrPrecondition, "etag 필요 (파일을 다시 불러온 뒤 저장하세요)")
+        return
+    }
+
+    release, busy := jobs.tryLock(projectKey(fullPath))
+    if release == nil {
+        apiBusy(c, busy)
+        return
+    }
+    defer release()
+
+    current, err := ioutil.ReadFile(fullPath)
+    if err != nil {
+        apiError(c, http.StatusNotFound, apiErrNotFound, "파일을 읽을 수 없습니다: "+req.Path)
+        return
+    }
+    ev := newAudit(c, "save", req.Path)
+    if etag != fileETag(current) {
+        ev.record(errors.New("동시 편집 충돌"))
+        apiError(c, http.StatusConflict, apiErrConflict, "파일을 불러온 이후 다른 사용자가 변경했습니다.",
+            gin.H{"etag": fileETag(current), "current": string(current)})
+        return
+    }
+    if !req.Force {
+        if errs := validateContent(fullPath, req.Content); len(errs) > 0 {
+            ev.record(fmt.Errorf("검증 실패 (%d건)", len(errs)))
+            apiError(c, http.StatusUnprocessableEntity, apiErrValidation, "검증 실패: 저장하지 않았습니다.", gin.H{"errors": errs})
+            return
+        }
+    }
+    meta := revisionMeta{Author: currentUser(c).Email, Message: req.Message, Action: "save"}
+    revID, err := revisions.Commit(fullPath, []byte(req.Content), meta)
+    ev.Backup = revID
+    ev.record(err)
+    if err != nil {
+        apiError(c, http.StatusInternalServerError, apiErrInternal, err.Error())
+        return
+    }
+    newTag := fileETag([]byte(req.Content))
+    c.Header("ETag", newTag)
+    c.JSON(http.StatusOK, gin.H{"path": req.Path, "etag": newTag, "revision": revID})
+}
+
+// ------------------------------------------------------
+// 33-2. 백업(리비전), 상태, 재시작, 작업
+// ------------------------------------------------------
+
+// GET /api/v1/backups?path=
+func apiListBackups(c *gin.Context) {
+    p := c.Query("path")
+    fullPath, ok := apiTarget(c, "path", p, permRead)
+    if !ok {
+        return
+    }
+    revs, err := revisions.List(fullPath)
+    if err != nil {
+        apiError(c, http.StatusInternalServerError, apiErrInternal, fmt.Sprintf("백업 목록 조회 오류: %v", err))
+        return
+    }
+    if revs == nil {
+        revs = []revision{}
+    }
+    c.JSON(http.StatusOK, gin.H{"path": p, "backups": revs})
+}
+
+type apiRollbackRequest struct {
+    Path     string `json:"path"`
+    Backup   string `json:"backup"` // GET /api/v1/backups 의 id
+    Message  string `json:"message"`
+    Strategy string `json:"strategy"` // 비우면 프로젝트 기본값
+    Wait     *bool  `json:"wait"`
+}
+
+// POST /api/v1/backups/rollback: 202 + 작업
+func apiRollback(c *gin.Context) {
+    var req apiRollbackRequest
+    if !bindAPIJSON(c, &req) {
+        return
+    }
+    fullPath, ok := apiTarget(c, "path", req.Path, permOperate)
+    if !ok {
+        return
+    }
+    if req.Backup == "" {
+        apiError(c, http.StatusBadRequest, apiErrBadRequest, "backup 필요", gin.H{"field": "backup"})
+        return
+    }
+    if _, err := revisions.Read(fullPath, req.Backup); err != nil {
+        apiError(c, http.StatusNotFound, apiErrNotFound, "백업을 찾을 수 없습니다: "+req.Backup)
+        return
+    }
+    opts, err := applyOptionsFor(fullPath, req.Strategy, req.Wait)
+    if err != nil {
+        apiError(c, http.StatusBadRequest, apiErrBadRequest, err.Error(), gin.H{"field": "strategy"})
+        return
+    }
+    apiJobAccepted(c, submitRollback(c, req.Path, fullPath, req.Backup, req.Message, opts))
+}
+
+// GET /api/v1/status?path=
+func apiStatus(c *gin.Context) {
+    p := c.Query("path")
+    fullPath, ok := apiTarget(c, "path", p, permRead)
+    if !ok {
+        return
+    }
+    services, err := composeStatus(fullPath)
+    if err != nil {
+        apiError(c, http.StatusInternalServerError, apiErrInternal, err.Error())
+        return
+    }
+    c.JSON(http.StatusOK, projectStatus{Path: p, State: summarizeStatus(services), Services: services})
+}
+
+type apiRestartRequest struct {
+    Path     string `json:"path"`
+    Strategy string `json:"strategy"`
+    Wait     *bool  `json:"wait"`
+}
+
+// POST /api/v1/restart: 202 + 작업
+func apiRestart(c *gin.Context) {
+    var req apiRestartRequest
+    if !bindAPIJSON(c, &req) {
+        return
+    }
+    fullPath, ok := apiTarget(c, "path", req.Path, permOperate)
+    if !ok {
+        return
+    }
+    opts, err := applyOptionsFor(fullPath, req.Strategy, req.Wait)
+    if err != nil {
+        apiError(c, http.StatusBadRequest, apiErrBadRequest, err.Error(), gin.H{"field": "strategy"})
+        return
+    }
+    apiJobAccepted(c, submitRestart(c, req.Path, fullPath, opts))
+}
+
+// GET /api/v1/jobs/:id (출력 포함)
+func apiGetJob(c *gin.Context) {
+    j := jobs.get(c.Param("id"))
+    if j == nil || !canAccess(c, j.Project, permRead) {
+        apiError(c, http.StatusNotFound, apiErrNotFound, "작업을 찾을 수 없습니다.")
+        return
+    }
+    c.JSON(http.StatusOK, j.view(true))
+}
+
+// ------------------------------------------------------
+// 33-3. 사용자 (어드민)
+// ------------------------------------------------------
+
+type apiUser struct {
+    Email      string            `json:"email"`
+    Role       string            `json:"role"`
+    Grants     map[string]string `json:"grants"`
+    TwoFactor  bool              `json:"twoFactor"`
+    Require2FA bool              `json:"require2FA"`
+}
+
+// GET /api/v1/users
+func apiListUsers(c *gin.Context) {
+    list := []apiUser{}
+    for _, u := range users {
+        grants := u.Grants
+        if grants == nil {
+            grants = map[string]string{}
+        }
+        list = append(list, apiUser{
+            Email:      u.Email,
+            Role:       u.Role,
+            Grants:     grants,
+            TwoFactor:  u.TOTPSecret != "",
+            Require2FA: u.Require2FA,
+        })
+    }
+    sort.Slice(list, func(i, j int) bool { return list[i].Email < list[j].Email })
+    c.JSON(http.StatusOK, gin.H{"users": list})
+}
+
+// PUT /api/v1/users/:email/role  {"role": "operator"}
+func apiUpdateUserRole(c *gin.Context) {
+    var req struct {
+        Role string `json:"role"`
+    }
+    if !bindAPIJSON(c, &req) {
+        return
+    }
+    if !validRole(req.Role) {
+        apiError(c, http.StatusBadRequest, apiErrBadRequest, "알 수 없는 역할: "+req.Role,
+            gin.H{"field": "role", "allowed": roles})
+        return
+    }
+    email := c.Param("email")
+    if status, err := changeUserRole(c, email, req.Role); err != nil {
+        code := apiErrInternal
+        switch status {
+        case http.StatusNotFound:
+            code = apiErrNotFound
+        case http.StatusConflict:
+            code = apiErrConflict
+        }
+        apiError(c, status, code, err.Error())
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"email": email, "role": req.Role})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ------------------------------------------------------
+// 22. 적용 전략 (전체 재시작 / 무중단 적용)
+// ------------------------------------------------------
+
+// 적용 전략
+const (
+    strategyRestart = "restart" // down 후 up -d (전체 재시작, 기본값)
+    strategyApply   = "apply"   // up -d --remove-orphans (변경된 서비스만 재생성)
+)
+
+// 변경 내용을 스택에 반영하는 방법
+type applyOptions struct {
+    Strategy string `json:"strategy"`
+    Wait     bool   `json:"wait"` // apply 전략에서 --wait 로 헬스체크 통과까지 대기
+}
+
+func validStrategy(s string) bool {
+    return s == strategyRestart || s == strategyApply
+}
+
+func (o applyOptions) String() string {
+    switch {
+    case o.Strategy == strategyApply && o.Wait:
+        return "무중단 적용 (up -d --remove-orphans --wait)"
+    case o.Strategy == strategyApply:
+        return "무중단 적용 (up -d --remove-orphans)"
+    default:
+        return "전체 재시작 (down → up -d)"
+    }
+}
+
+// projectApplyOptions: 파일이 속한 프로젝트의 기본 적용 전략 (설정이 없으면 전체 재시작)
+func projectApplyOptions(fullPath string) applyOptions {
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+    if s, ok := registry.Settings[projectKey(fullPath)]; ok && s != nil && validStrategy(s.Strategy) {
+        return s.applyOptions
+    }
+    return applyOptions{Strategy: strategyRestart}
+}
+
+// applyOptionsFromRequest: 요청의 strategy/wait 값 (없으면 프로젝트 기본값)
+func applyOptionsFromRequest(c *gin.Context, fullPath string) (applyOptions, error) {
+    var wait *bool
+    if w, ok := c.GetPostForm("wait"); ok {
+        v := w == "1"
+        wait = &v
+    }
+    return applyOptionsFor(fullPath, c.PostForm("strategy"), wait)
+}
+
+// applyOptionsFor: 프로젝트 기본값에 요청한 전략/대기 여부를 덮어씀 (빈 값/nil 이면 기본값 유지)
+func applyOptionsFor(fullPath, strategy string, wait *bool) (applyOptions, error) {
+    opts := projectApplyOptions(fullPath)
+    if strategy != "" {
+        if !validStrategy(strategy) {
+            return opts, fmt.Errorf("알 수 없는 적용 전략: %s", strategy)
+        }
+        opts.Strategy = strategy
+    }
+    if wait != nil {
+        opts.Wait = *wait
+    }
+    return opts, nil
+}
+
+// applyCompose: 선택한 전략으로 compose 파일을 스택에 반영 (사용한 전략을 출력에 기록)
+func applyCompose(w io.Writer, filePath string, opts applyOptions) error {
+    if composeCommand == "" {
+        return fmt.Errorf("docker compose 명령이 감지되지 않았습니다.")
+    }
+    fmt.Fprintf(w, "[적용 전략] %s\n", opts)
+    if opts.Strategy != strategyApply {
+        return dockerComposeRestart(w, filePath)
+    }
+    args := []string{"up", "-d", "--remove-orphans"}
+    if opts.Wait {
+        if composeCommand == "docker-compose" {
+            // v1 에는 --wait 옵션이 없음
+            fmt.Fprintln(w, "[경고] docker-compose(v1) 는 --wait 를 지원하지 않아 생략합니다.")
+        } else {
+            args = append(args, "--wait")
+        }
+    }
+    return runCompose(w, filePath, args...)
+}
+
+// 프로젝트 설정 조회: GET ?path=파일
+func getProjectSettingsAPI(c *gin.Context) {
+    fullPath, err := resolvePath(c.Query("path"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, projectApplyOptions(fullPath))
+}
+
+// 프로젝트 설정 저장: POST path, strategy, wait
+func saveProjectSettingsAPI(c *gin.Context) {
+    fullPath, err := resolvePath(c.PostForm("path"))
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
+    opts, err := applyOptionsFromRequest(c, fullPath)
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
+
+    registryMu.Lock()
+    defer registryMu.Unlock()
+    if registry.Settings == nil {
+        registry.Settings = make(map[string]*projectSettings)
+    }
+    key := projectKey(fullPath)
+    s := registry.Settings[key]
+    if s == nil {
+        s = &projectSettings{}
+        registry.Settings[key] = s
+    }
+    s.applyOptions = opts
+    if err := saveProjects(); err != nil {
+        c.String(http.StatusInternalServerError, fmt.Sprintf("프로젝트 설정 저장 오류: %v", err))
+        return
+    }
+    c.String(http.StatusOK, "프로젝트 기본 적용 전략: "+opts.String())
+}
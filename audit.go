@@ -0,0 +1,235 @@
+package main
+
+import (
+    "bufio"
+    "encoding/csv"
+    "encoding/json"
+    "log"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ------------------------------------------------------
+// 25. 감사 로그 (로그인, 편집, 재시작, 롤백, 권한 변경 등)
+// ------------------------------------------------------
+
+// 감사 로그 파일 (JSON lines, 추가만 함). .env 의 audit_log 로 변경 가능
+var auditFile = "audit.log"
+
+// 결과
+const (
+    auditSuccess = "success"
+    auditFailure = "failure"
+    auditQueued  = "queued" // 백그라운드 작업으로 넘김 (완료 시 별도 기록)
+)
+
+type auditEntry struct {
+    Time   time.Time `json:"time"`
+    User   string    `json:"user"`
+    IP     string    `json:"ip"`
+    Action string    `json:"action"`
+    Target string    `json:"target,omitempty"`
+    Backup string    `json:"backup,omitempty"`
+    Job    string    `json:"job,omitempty"`
+    Token  string    `json:"token,omitempty"` // API 토큰으로 한 요청이면 토큰 ID
+    Detail string    `json:"detail,omitempty"` // 예: 권한 변경 내용
+    Result string    `json:"result"`
+    Error  string    `json:"error,omitempty"`
+}
+
+// 감사 로그 페이지의 작업 필터 목록
+var auditActions = []string{
+    "login", "register", "password-reset", "save", "save+restart", "restart", "rollback", "auto-rollback",
+    "create-file", "create-dir", "role", "grants", "unlock", "2fa", "require-2fa", "token-create", "token-revoke", "project", "project-delete",
+    "service:start", "service:stop", "service:restart", "service:pull", "service:recreate",
+}
+
+var auditMu sync.Mutex
+
+// writeAudit: 감사 로그 한 줄 추가 (실패해도 요청은 계속 진행)
+func writeAudit(e auditEntry) {
+    if e.Time.IsZero() {
+        e.Time = time.Now()
+    }
+    data, err := json.Marshal(e)
+    if err != nil {
+        log.Printf("[경고] 감사 로그 기록 실패: %v", err)
+        return
+    }
+    auditMu.Lock()
+    defer auditMu.Unlock()
+    f, err := os.OpenFile(auditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+    if err != nil {
+        log.Printf("[경고] 감사 로그 기록 실패: %v", err)
+        return
+    }
+    defer f.Close()
+    if _, err := f.Write(append(data, '\n')); err != nil {
+        log.Printf("[경고] 감사 로그 기록 실패: %v", err)
+    }
+}
+
+// newAudit: 요청 정보(사용자, IP)를 담은 감사 항목. 작업 완료 후 기록할 때도 그대로 사용한다.
+func newAudit(c *gin.Context, action, target string) auditEntry {
+    e := auditEntry{IP: c.ClientIP(), Action: action, Target: target}
+    if u := currentUser(c); u != nil {
+        e.User = u.Email
+    }
+    if t := requestToken(c); t != nil {
+        e.Token = t.ID
+    }
+    return e
+}
+
+// record: err 에 따라 성공/실패로 기록
+func (e auditEntry) record(err error) {
+    e.Result = auditSuccess
+    if err != nil {
+        e.Result = auditFailure
+        e.Error = err.Error()
+    }
+    writeAudit(e)
+}
+
+// queued: 백그라운드 작업으로 넘겼음을 기록
+func (e auditEntry) queued(j *job) {
+    e.Result = auditQueued
+    e.Job = j.ID
+    writeAudit(e)
+}
+
+// 감사 로그 조회 조건
+type auditFilter struct {
+    User   string
+    Action string
+    Target string // 부분 일치
+    Result string
+    From   time.Time
+    To     time.Time // 이 날짜까지 포함
+}
+
+func auditFilterFromQuery(c *gin.Context) auditFilter {
+    f := auditFilter{
+        User:   strings.TrimSpace(c.Query("user")),
+        Action: strings.TrimSpace(c.Query("action")),
+        Target: strings.TrimSpace(c.Query("target")),
+        Result: strings.TrimSpace(c.Query("result")),
+    }
+    if t, err := time.ParseInLocation("2006-01-02", c.Query("from"), time.Local); err == nil {
+        f.From = t
+    }
+    if t, err := time.ParseInLocation("2006-01-02", c.Query("to"), time.Local); err == nil {
+        f.To = t.AddDate(0, 0, 1)
+    }
+    return f
+}
+
+func (f auditFilter) match(e auditEntry) bool {
+    switch {
+    case f.User != "" && e.User != f.User:
+        return false
+    case f.Action != "" && e.Action != f.Action:
+        return false
+    case f.Target != "" && !strings.Contains(e.Target, f.Target):
+        return false
+    case f.Result != "" && e.Result != f.Result:
+        return false
+    case !f.From.IsZero() && e.Time.Before(f.From):
+        return false
+    case !f.To.IsZero() && !e.Time.Before(f.To):
+        return false
+    }
+    return true
+}
+
+// readAudit: 조건에 맞는 항목 (최신순, limit <= 0 이면 전부)
+func readAudit(f auditFilter, limit int) ([]auditEntry, error) {
+    auditMu.Lock()
+    defer auditMu.Unlock()
+    file, err := os.Open(auditFile)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, nil
+        }
+        return nil, err
+    }
+    defer file.Close()
+
+    var result []auditEntry
+    scanner := bufio.NewScanner(file)
+    scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+    for scanner.Scan() {
+        var e auditEntry
+        if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+            continue
+        }
+        if f.match(e) {
+            result = append(result, e)
+        }
+    }
+    // 파일은 시간순이므로 뒤집어서 최신순으로
+    for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+        result[i], result[j] = result[j], result[i]
+    }
+    if limit > 0 && len(result) > limit {
+        result = result[:limit]
+    }
+    return result, scanner.Err()
+}
+
+// 화면에 표시하는 최대 항목 수 (CSV 내보내기는 제한 없음)
+const auditPageLimit = 500
+
+// 감사 로그 페이지 (어드민)
+func auditPage(c *gin.Context) {
+    f := auditFilterFromQuery(c)
+    entries, err := readAudit(f, auditPageLimit)
+    if err != nil {
+        c.String(http.StatusInternalServerError, "감사 로그 읽기 오류: "+err.Error())
+        return
+    }
+    c.HTML(http.StatusOK, "audit.html", gin.H{
+        "Entries": entries,
+        "Actions": auditActions,
+        "Limit":   auditPageLimit,
+        "Query":   c.Request.URL.RawQuery,
+        "User":    f.User,
+        "Action":  f.Action,
+        "Target":  f.Target,
+        "Result":  f.Result,
+        "From":    c.Query("from"),
+        "To":      c.Query("to"),
+    })
+}
+
+// 감사 로그 CSV 내보내기 (같은 조건)
+func auditCSV(c *gin.Context) {
+    entries, err := readAudit(auditFilterFromQuery(c), 0)
+    if err != nil {
+        c.String(http.StatusInternalServerError, "감사 로그 읽기 오류: "+err.Error())
+        return
+    }
+    c.Header("Content-Type", "text/csv; charset=utf-8")
+    c.Header("Content-Disposition", "attachment; filename=audit_"+time.Now().Format("20060102_150405")+".csv")
+    // 엑셀에서 한글이 깨지지 않도록 BOM
+    c.Writer.Write([]byte("\xEF\xBB\xBF"))
+    w := csv.NewWriter(c.Writer)
+    w.Write([]string{"time", "user", "ip", "action", "target", "backup", "job", "token", "detail", "result", "error"})
+    for _, e := range entries {
+        w.Write([]string{e.Time.Format(time.RFC3339), csvCell(e.User), e.IP, e.Action, csvCell(e.Target), csvCell(e.Backup), e.Job, e.Token, csvCell(e.Detail), e.Result, csvCell(e.Error)})
+    }
+    w.Flush()
+}
+
+// csvCell: 스프레드시트에서 수식으로 해석되지 않도록 = + - @ 로 시작하는 값 앞에 ' 추가
+func csvCell(s string) string {
+    if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+        return "'" + s
+    }
+    return s
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+    "crypto/subtle"
+    "net/http"
+
+    "github.com/gin-contrib/sessions"
+    "github.com/gin-gonic/gin"
+)
+
+// ------------------------------------------------------
+// 29. CSRF 방지 (세션별 토큰)
+// ------------------------------------------------------
+
+// 토큰을 담는 세션 키, 요청 헤더, 폼 필드
+const (
+    csrfSessionKey = "csrf_token"
+    csrfHeader     = "X-CSRF-Token"
+    csrfFormField  = "csrf_token"
+)
+
+// csrfToken: 현재 세션의 CSRF 토큰 (없으면 만들어 세션에 저장)
+// 로그인하면 세션을 새로 시작하므로 토큰도 새로 발급된다.
+func csrfToken(c *gin.Context) string {
+    sess := sessions.Default(c)
+    if token, ok := sess.Get(csrfSessionKey).(string); ok && token != "" {
+        return token
+    }
+    token := randomHex(32)
+    sess.Set(csrfSessionKey, token)
+    sess.Save()
+    return token
+}
+
+// csrfProtect: GET/HEAD/OPTIONS 이외의 요청은 세션 토큰과 같은 값을
+// X-CSRF-Token 헤더 또는 csrf_token 폼 필드로 보내야 한다.
+func csrfProtect() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        switch c.Request.Method {
+        case http.MethodGet, http.MethodHead, http.MethodOptions:
+            c.Next()
+            return
+        }
+        // API 토큰 요청은 브라우저가 자동으로 붙이는 쿠키를 쓰지 않으므로 제외 (토큰 검증은 AuthRequired)
+        if bearerToken(c) != "" {
+            c.Next()
+            return
+        }
+        expected, _ := sessions.Default(c).Get(csrfSessionKey).(string)
+        got := c.GetHeader(csrfHeader)
+        if got == "" {
+            got = c.PostForm(csrfFormField)
+        }
+        if expected == "" || subtle.ConstantTimeCompare([]byte(got), []byte(expected)) != 1 {
+            if isAPIRequest(c) {
+                apiError(c, http.StatusForbidden, apiErrCSRF, "CSRF 토큰이 없거나 일치하지 않습니다. X-CSRF-Token 헤더를 보내거나 API 토큰을 사용하세요.")
+                return
+            }
+            c.String(http.StatusForbidden, "CSRF 토큰이 없거나 일치하지 않습니다. 페이지를 새로고침한 뒤 다시 시도하세요.")
+            c.Abort()
+            return
+        }
+        c.Next()
+    }
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "strings"
+    "testing"
+
+    "github.com/gin-gonic/gin"
+)
+
+// csrfTestRouter: GET /token 은 세션의 CSRF 토큰을 돌려주고, 나머지는 csrfProtect 를 통과하면 200
+func csrfTestRouter() *gin.Engine {
+    r := testRouter()
+    r.Use(csrfProtect())
+    r.GET("/token", func(c *gin.Context) { c.String(http.StatusOK, csrfToken(c)) })
+    ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+    r.POST("/console/api/save", ok)
+    r.DELETE("/console/api/save", ok)
+    r.HEAD("/console/api/save", ok)
+    r.POST("/api/v1/restart", ok)
+    return r
+}
+
+// newCSRFSession: 세션을 시작해 쿠키와 그 세션의 CSRF 토큰을 돌려준다
+func newCSRFSession(t *testing.T, r *gin.Engine) (cookie, token string) {
+    w := testRequest(r, http.MethodGet, "/token", nil, nil)
+    if w.Code != http.StatusOK {
+        t.Fatalf("토큰 발급 실패: %d", w.Code)
+    }
+    cookie = strings.Split(w.Header().Get("Set-Cookie"), ";")[0]
+    if cookie == "" {
+        t.Fatal("세션 쿠키가 없음")
+    }
+    // 같은 세션에서는 같은 토큰
+    if again := testRequest(r, http.MethodGet, "/token", nil, map[string]string{"Cookie": cookie}); again.Body.String() != w.Body.String() {
+        t.Fatalf("같은 세션의 토큰이 바뀜: %s -> %s", w.Body.String(), again.Body.String())
+    }
+    return cookie, w.Body.String()
+}
+
+func TestCSRFProtect(t *testing.T) {
+    r := csrfTestRouter()
+    cookie, token := newCSRFSession(t, r)
+    otherCookie, otherToken := newCSRFSession(t, r)
+    if token == otherToken {
+        t.Fatal("세션마다 다른 토큰이어야 함")
+    }
+    // 마지막 한 글자만 다른 토큰
+    wrong := token[:len(token)-1] + "0"
+    if wrong == token {
+        wrong = token[:len(token)-1] + "1"
+    }
+
+    tests := []struct {
+        name   string
+        method string
+        path   string
+        header map[string]string
+        form   url.Values
+        want   int
+    }{
+        {"헤더로 보낸 토큰", http.MethodPost, "/console/api/save",
+            map[string]string{"Cookie": cookie, csrfHeader: token}, url.Values{}, http.StatusOK},
+        {"폼 필드로 보낸 토큰", http.MethodPost, "/console/api/save",
+            map[string]string{"Cookie": cookie}, url.Values{csrfFormField: {token}}, http.StatusOK},
+        {"헤더가 폼보다 우선", http.MethodPost, "/console/api/save",
+            map[string]string{"Cookie": cookie, csrfHeader: "wrong"}, url.Values{csrfFormField: {token}}, http.StatusForbidden},
+        {"DELETE 도 검사", http.MethodDelete, "/console/api/save",
+            map[string]string{"Cookie": cookie}, nil, http.StatusForbidden},
+        {"GET 은 검사하지 않음", http.MethodGet, "/token",
+            map[string]string{"Cookie": cookie}, nil, http.StatusOK},
+        {"HEAD 는 검사하지 않음", http.MethodHead, "/console/api/save",
+            map[string]string{"Cookie": cookie}, nil, http.StatusOK},
+
+        {"토큰 없음", http.MethodPost, "/console/api/save",
+            map[string]string{"Cookie": cookie}, url.Values{}, http.StatusForbidden},
+        {"틀린 토큰", http.MethodPost, "/console/api/save",
+            map[string]string{"Cookie": cookie, csrfHeader: wrong}, url.Values{}, http.StatusForbidden},
+        {"다른 세션의 토큰", http.MethodPost, "/console/api/save",
+            map[string]string{"Cookie": otherCookie, csrfHeader: token}, url.Values{}, http.StatusForbidden},
+        {"세션 없이 보낸 토큰", http.MethodPost, "/console/api/save",
+            map[string]string{csrfHeader: token}, url.Values{}, http.StatusForbidden},
+        {"토큰을 발급받지 않은 세션에 빈 토큰", http.MethodPost, "/console/api/save",
+            map[string]string{csrfHeader: ""}, url.Values{csrfFormField: {""}}, http.StatusForbidden},
+
+        {"Bearer 요청은 제외", http.MethodPost, "/console/api/save",
+            map[string]string{"Authorization": "Bearer dcw_anything"}, url.Values{}, http.StatusOK},
+        {"Bearer 요청은 쿠키가 있어도 제외", http.MethodPost, "/api/v1/restart",
+            map[string]string{"Cookie": cookie, "Authorization": "bearer dcw_anything"}, url.Values{}, http.StatusOK},
+        {"빈 Bearer 는 제외하지 않음", http.MethodPost, "/console/api/save",
+            map[string]string{"Cookie": cookie, "Authorization": "Bearer "}, url.Values{}, http.StatusForbidden},
+        {"Basic 인증은 제외하지 않음", http.MethodPost, "/console/api/save",
+            map[string]string{"Cookie": cookie, "Authorization": "Basic dXNlcjpwYXNz"}, url.Values{}, http.StatusForbidden},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            w := testRequest(r, tt.method, tt.path, tt.form, tt.header)
+            if w.Code != tt.want {
+                t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+            }
+        })
+    }
+}
+
+func TestCSRFProtectAPIError(t *testing.T) {
+    r := csrfTestRouter()
+    cookie, _ := newCSRFSession(t, r)
+
+    // /api 아래는 JSON 오류
+    w := testRequest(r, http.MethodPost, "/api/v1/restart", url.Values{}, map[string]string{"Cookie": cookie})
+    if w.Code != http.StatusForbidden {
+        t.Fatalf("status = %d", w.Code)
+    }
+    var body struct {
+        Error apiErrorBody `json:"error"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error.Code != apiErrCSRF {
+        t.Fatalf("JSON 오류 응답이어야 함: %s (%v)", w.Body.String(), err)
+    }
+
+    // 콘솔 API 는 텍스트
+    w = testRequest(r, http.MethodPost, "/console/api/save", url.Values{}, map[string]string{"Cookie": cookie})
+    if strings.HasPrefix(w.Body.String(), "{") {
+        t.Fatalf("콘솔 API 는 텍스트 응답이어야 함: %s", w.Body.String())
+    }
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+    "fmt"
+    "html"
+    "io/ioutil"
+    "net/http"
+    "path/filepath"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ------------------------------------------------------
+// 17. 파일/백업 간 비교 (unified diff + side-by-side HTML)
+// ------------------------------------------------------
+
+// diff 결과 한 줄: Op 는 ' '(동일), '-'(삭제), '+'(추가)
+type diffLine struct {
+    Op    byte
+    Text  string
+    ALine int // 원본 줄 번호 (추가 줄이면 0)
+    BLine int // 대상 줄 번호 (삭제 줄이면 0)
+}
+
+// LCS 테이블 크기 제한 (이보다 크면 변경 구간 전체를 교체로 처리)
+const maxDiffCells = 4000000
+
+func splitLines(s string) []string {
+    if s == "" {
+        return nil
+    }
+    s = strings.TrimSuffix(s, "\n")
+    return strings.Split(s, "\n")
+}
+
+// diffLines: 공통 접두/접미를 제외한 구간에 LCS 를 적용해 줄 단위 diff 계산
+func diffLines(a, b []string) []diffLine {
+    var result []diffLine
+
+    // 공통 접두
+    pre := 0
+    for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+        result = append(result, diffLine{Op: ' ', Text: a[pre], ALine: pre + 1, BLine: pre + 1})
+        pre++
+    }
+    // 공통 접미
+    suf := 0
+    for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+        suf++
+    }
+    ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+
+    if len(ma)*len(mb) > maxDiffCells {
+        for i, t := range ma {
+            result = append(result, diffLine{Op: '-', Text: t, ALine: pre + i + 1})
+        }
+        for j, t := range mb {
+            result = append(result, diffLine{Op: '+', Text: t, BLine: pre + j + 1})
+        }
+    } else {
+        // lcs[i][j] = ma[i:], mb[j:] 의 LCS 길이
+        lcs := make([][]int, len(ma)+1)
+        for i := range lcs {
+            lcs[i] = make([]int, len(mb)+1)
+        }
+        for i := len(ma) - 1; i >= 0; i-- {
+            for j := len(mb) - 1; j >= 0; j-- {
+                if ma[i] == mb[j] {
+                    lcs[i][j] = lcs[i+1][j+1] + 1
+                } else if lcs[i+1][j] >= lcs[i][j+1] {
+                    lcs[i][j] = lcs[i+1][j]
+                } else {
+                    lcs[i][j] = lcs[i][j+1]
+                }
+            }
+        }
+        i, j := 0, 0
+        for i < len(ma) || j < len(mb) {
+            switch {
+            case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+                result = append(result, diffLine{Op: ' ', Text: ma[i], ALine: pre + i + 1, BLine: pre + j + 1})
+                i++
+                j++
+            case j >= len(mb) || (i < len(ma) && lcs[i+1][j] >= lcs[i][j+1]):
+                result = append(result, diffLine{Op: '-', Text: ma[i], ALine: pre + i + 1})
+                i++
+            default:
+                result = append(result, diffLine{Op: '+', Text: mb[j], BLine: pre + j + 1})
+                j++
+            }
+        }
+    }
+
+    for k := suf; k > 0; k-- {
+        ai, bi := len(a)-k, len(b)-k
+        result = append(result, diffLine{Op: ' ', Text: a[ai], ALine: ai + 1, BLine: bi + 1})
+    }
+    return result
+}
+
+// unifiedDiff: 변경 주변 context 줄을 포함한 unified diff 문자열 생성
+func unifiedDiff(fromName, toName string, lines []diffLine, context int) string {
+    // 변경된 줄 인덱스를 기준으로 hunk 범위 계산
+    var hunks [][2]int
+    for i, l := range lines {
+        if l.Op == ' ' {
+            continue
+        }
+        start, end := i-context, i+context+1
+        if start < 0 {
+            start = 0
+        }
+        if end > len(lines) {
+            end = len(lines)
+        }
+        if n := len(hunks); n > 0 && start <= hunks[n-1][1] {
+            hunks[n-1][1] = end
+        } else {
+            hunks = append(hunks, [2]int{start, end})
+        }
+    }
+    if len(hunks) == 0 {
+        return ""
+    }
+
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+    for _, h := range hunks {
+        aStart, bStart, aLen, bLen := 0, 0, 0, 0
+        for _, l := range lines[h[0]:h[1]] {
+            if l.Op != '+' {
+                if aStart == 0 {
+                    aStart = l.ALine
+                }
+                aLen++
+            }
+            if l.Op != '-' {
+                if bStart == 0 {
+                    bStart = l.BLine
+                }
+                bLen++
+            }
+        }
+        // 빈 구간이면 직전 줄 번호 사용 (GNU diff 와 동일)
+        if aLen == 0 {
+            aStart = hunkAnchor(lines, h[0], true)
+        }
+        if bLen == 0 {
+            bStart = hunkAnchor(lines, h[0], false)
+        }
+        fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+        for _, l := range lines[h[0]:h[1]] {
+            sb.WriteByte(l.Op)
+            sb.WriteString(l.Text)
+            sb.WriteByte('\n')
+        }
+    }
+    return sb.String()
+}
+
+// hunkAnchor: idx 이전의 마지막 원본(또는 대상) 줄 번호
+func hunkAnchor(lines []diffLine, idx int, side bool) int {
+    for k := idx - 1; k >= 0; k-- {
+        if side && lines[k].ALine > 0 {
+            return lines[k].ALine
+        }
+        if !side && lines[k].BLine > 0 {
+            return lines[k].BLine
+        }
+    }
+    return 0
+}
+
+// sideBySideHTML: 좌(원본)/우(대상) 2열 HTML 테이블 생성
+// 연속된 삭제/추가 블록은 같은 행에 나란히 배치한다.
+func sideBySideHTML(fromName, toName string, lines []diffLine) string {
+    var sb strings.Builder
+    sb.WriteString(`<table class="diff"><tr><th colspan="2">` + html.EscapeString(fromName) +
+        `</th><th colspan="2">` + html.EscapeString(toName) + `</th></tr>`)
+    cell := func(n int, text, class string) string {
+        num := ""
+        if n > 0 {
+            num = fmt.Sprint(n)
+        }
+        return fmt.Sprintf(`<td class="ln">%s</td><td class="%s">%s</td>`, num, class, html.EscapeString(text))
+    }
+    for i := 0; i < len(lines); {
+        if lines[i].Op == ' ' {
+            l := lines[i]
+            sb.WriteString("<tr>" + cell(l.ALine, l.Text, "same") + cell(l.BLine, l.Text, "same") + "</tr>")
+            i++
+            continue
+        }
+        var dels, adds []diffLine
+        for i < len(lines) && lines[i].Op == '-' {
+            dels = append(dels, lines[i])
+            i++
+        }
+        for i < len(lines) && lines[i].Op == '+' {
+            adds = append(adds, lines[i])
+            i++
+        }
+        for k := 0; k < len(dels) || k < len(adds); k++ {
+            sb.WriteString("<tr>")
+            if k < len(dels) {
+                sb.WriteString(cell(dels[k].ALine, dels[k].Text, "del"))
+            } else {
+                sb.WriteString(cell(0, "", "empty"))
+            }
+            if k < len(adds) {
+                sb.WriteString(cell(adds[k].BLine, adds[k].Text, "add"))
+            } else {
+                sb.WriteString(cell(0, "", "empty"))
+            }
+            sb.WriteString("</tr>")
+        }
+    }
+    sb.WriteString("</table>")
+    return sb.String()
+}
+
+// readRevision: name 이 비어있으면 현재 파일, 아니면 리비전 저장소의 내용
+func readRevision(fullPath, name string) (string, string, error) {
+    if name == "" {
+        data, err := ioutil.ReadFile(fullPath)
+        return "(현재) " + filepath.Base(fullPath), string(data), err
+    }
+    data, err := revisions.Read(fullPath, name)
+    return name, string(data), err
+}
+
+// 비교 결과 JSON 응답
+func writeDiff(c *gin.Context, fromName, fromText, toName, toText string) {
+    lines := diffLines(splitLines(fromText), splitLines(toText))
+    unified := unifiedDiff(fromName, toName, lines, 3)
+    c.JSON(http.StatusOK, gin.H{
+        "from":    fromName,
+        "to":      toName,
+        "changed": unified != "",
+        "unified": unified,
+        "html":    sideBySideHTML(fromName, toName, lines),
+    })
+}
+
+// 비교 조회 (GET): from/to 는 리비전 ID, 비어있으면 현재 파일
+func diffAPI(c *gin.Context) {
+    p := c.Query("path")
+    if p == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "path 파라미터 필요"})
+        return
+    }
+    fullPath, err := resolvePath(p)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    fromName, fromText, err := readRevision(fullPath, c.Query("from"))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("비교 대상 읽기 오류: %v", err)})
+        return
+    }
+    toName, toText, err := readRevision(fullPath, c.Query("to"))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("비교 대상 읽기 오류: %v", err)})
+        return
+    }
+    writeDiff(c, fromName, fromText, toName, toText)
+}
+
+// 저장 전 미리보기 (POST): from(기본 현재 파일) 과 편집 중인 content 비교
+func diffPendingAPI(c *gin.Context) {
+    p := c.PostForm("path")
+    if p == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "path 파라미터 필요"})
+        return
+    }
+    fullPath, err := resolvePath(p)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    fromName, fromText, err := readRevision(fullPath, c.PostForm("from"))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("비교 대상 읽기 오류: %v", err)})
+        return
+    }
+    writeDiff(c, fromName, fromText, "(편집 중) "+filepath.Base(fullPath), c.PostForm("content"))
+}
+
+// ------------------------------------------------------
+// 17-1. 3-way 병합 (편집 충돌 시 사용)
+// ------------------------------------------------------
+
+// matchIndex: diff 결과에서 원본 줄 -> 대상 줄 인덱스 매핑 (대응 없으면 -1)
+func matchIndex(lines []diffLine, n int) []int {
+    m := make([]int, n)
+    for i := range m {
+        m[i] = -1
+    }
+    for _, l := range lines {
+        if l.Op == ' ' {
+            m[l.ALine-1] = l.BLine - 1
+        }
+    }
+    return m
+}
+
+func equalLines(a, b []string) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for i := range a {
+        if a[i] != b[i] {
+            return false
+        }
+    }
+    return true
+}
+
+// merge3: base 에서 각각 변경된 mine/theirs 를 병합
+// 양쪽이 같은 구간을 다르게 바꾼 경우 충돌 표시(<<<<<<< ======= >>>>>>>)를 남기고 conflict=true
+func merge3(base, mine, theirs []string) ([]string, bool) {
+    ma := matchIndex(diffLines(base, mine), len(base))
+    mb := matchIndex(diffLines(base, theirs), len(base))
+
+    var out []string
+    conflict := false
+    i, j, k := 0, 0, 0
+    for i < len(base) || j < len(mine) || k < len(theirs) {
+        // 양쪽 모두에서 그대로 유지된 다음 base 줄 찾기
+        next := i
+        for next < len(base) && (ma[next] < j || mb[next] < k) {
+            next++
+        }
+        if next == i && i < len(base) && ma[i] == j && mb[i] == k {
+            out = append(out, base[i])
+            i, j, k = i+1, j+1, k+1
+            continue
+        }
+
+        nj, nk := len(mine), len(theirs)
+        if next < len(base) {
+            nj, nk = ma[next], mb[next]
+        }
+        b, m, t := base[i:next], mine[j:nj], theirs[k:nk]
+        switch {
+        case equalLines(m, b):
+            out = append(out, t...)
+        case equalLines(t, b), equalLines(m, t):
+            out = append(out, m...)
+        default:
+            conflict = true
+            out = append(out, "<<<<<<< 내 변경")
+            out = append(out, m...)
+            out = append(out, "=======")
+            out = append(out, t...)
+            out = append(out, ">>>>>>> 디스크의 현재 파일")
+        }
+        i, j, k = next, nj, nk
+    }
+    return out, conflict
+}
+
+// writeConflict: 409 응답으로 base->내 변경, base->현재 파일 diff 와 3-way 병합 결과 반환
+// base 는 편집을 시작할 때 불러온 내용 (클라이언트가 함께 전송)
+func writeConflict(c *gin.Context, fullPath, base, mine, theirs string) {
+    name := filepath.Base(fullPath)
+    baseLines, mineLines, theirLines := splitLines(base), splitLines(mine), splitLines(theirs)
+    mineDiff := diffLines(baseLines, mineLines)
+    theirDiff := diffLines(baseLines, theirLines)
+    merged, conflict := merge3(baseLines, mineLines, theirLines)
+    mergedText := strings.Join(merged, "\n")
+    if len(merged) > 0 {
+        mergedText += "\n"
+    }
+    c.JSON(http.StatusConflict, gin.H{
+        "error":       "파일을 불러온 이후 다른 사용자가 변경했습니다.",
+        "etag":        fileETag([]byte(theirs)),
+        "current":     theirs,
+        "mineDiff":    unifiedDiff("(불러온 내용) "+name, "(내 변경) "+name, mineDiff, 3),
+        "theirsDiff":  unifiedDiff("(불러온 내용) "+name, "(현재 파일) "+name, theirDiff, 3),
+        "mineHtml":    sideBySideHTML("(불러온 내용) "+name, "(내 변경) "+name, mineDiff),
+        "theirsHtml":  sideBySideHTML("(불러온 내용) "+name, "(현재 파일) "+name, theirDiff),
+        "merged":      mergedText,
+        "hasConflict": conflict,
+    })
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+    "strings"
+    "testing"
+)
+
+// lines: "a b c" -> ["a", "b", "c"] (빈 문자열은 빈 파일)
+func lines(s string) []string {
+    if s == "" {
+        return nil
+    }
+    return strings.Fields(s)
+}
+
+func TestDiffLines(t *testing.T) {
+    tests := []struct {
+        name    string
+        a, b    string
+        changes int // '-' 와 '+' 줄 수의 합 (최소 편집이어야 함)
+    }{
+        {"동일", "a b c", "a b c", 0},
+        {"빈 파일끼리", "", "", 0},
+        {"새 파일", "", "a b", 2},
+        {"전부 삭제", "a b", "", 2},
+        {"중간 변경", "a b c", "a x c", 2},
+        {"앞에 추가", "b c", "a b c", 1},
+        {"끝에 추가", "a b", "a b c", 1},
+        {"중간 삭제", "a b c d", "a d", 2},
+        {"순서 바꿈", "a b c", "c a b", 2},
+        {"반복 줄", "a a b a", "a b a a", 2},
+        {"여러 구간", "a b c d e f", "a x c d y f", 4},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            a, b := lines(tt.a), lines(tt.b)
+            result := diffLines(a, b)
+
+            // ' ' + '-' 는 a, ' ' + '+' 는 b 를 순서대로 재구성해야 한다
+            var gotA, gotB []string
+            changes := 0
+            for _, l := range result {
+                switch l.Op {
+                case ' ':
+                    gotA, gotB = append(gotA, l.Text), append(gotB, l.Text)
+                    if a[l.ALine-1] != l.Text || b[l.BLine-1] != l.Text {
+                        t.Errorf("줄 번호가 맞지 않음: %+v", l)
+                    }
+                case '-':
+                    gotA = append(gotA, l.Text)
+                    changes++
+                    if l.BLine != 0 || a[l.ALine-1] != l.Text {
+                        t.Errorf("삭제 줄 번호가 맞지 않음: %+v", l)
+                    }
+                case '+':
+                    gotB = append(gotB, l.Text)
+                    changes++
+                    if l.ALine != 0 || b[l.BLine-1] != l.Text {
+                        t.Errorf("추가 줄 번호가 맞지 않음: %+v", l)
+                    }
+                default:
+                    t.Fatalf("알 수 없는 Op %q", l.Op)
+                }
+            }
+            if !equalLines(gotA, a) || !equalLines(gotB, b) {
+                t.Fatalf("재구성 실패: a=%v (원본 %v), b=%v (원본 %v)", gotA, a, gotB, b)
+            }
+            if changes != tt.changes {
+                t.Errorf("변경 줄 수 = %d, want %d (%v)", changes, tt.changes, result)
+            }
+        })
+    }
+}
+
+func TestUnifiedDiff(t *testing.T) {
+    got := unifiedDiff("a.yml", "b.yml", diffLines(lines("a b c d e f g h"), lines("a b c X e f g h")), 1)
+    want := "--- a.yml\n+++ b.yml\n@@ -3,3 +3,3 @@\n c\n-d\n+X\n e\n"
+    if got != want {
+        t.Fatalf("unifiedDiff =\n%s\nwant\n%s", got, want)
+    }
+    if got := unifiedDiff("a", "b", diffLines(lines("a b"), lines("a b")), 3); got != "" {
+        t.Fatalf("변경이 없으면 빈 문자열이어야 함: %q", got)
+    }
+}
+
+func TestMerge3(t *testing.T) {
+    tests := []struct {
+        name              string
+        base, mine, their string
+        want              string
+        conflict          bool
+    }{
+        {"변경 없음", "a b c", "a b c", "a b c", "a b c", false},
+        {"내 쪽만 변경", "a b c", "a X c", "a b c", "a X c", false},
+        {"상대 쪽만 변경", "a b c", "a b c", "a b Y", "a b Y", false},
+        {"서로 다른 줄 변경", "a b c d e", "a X c d e", "a b c d Y", "a X c d Y", false},
+        {"같은 변경", "a b c", "a X c", "a X c", "a X c", false},
+        {"앞과 끝에 각각 추가", "b c", "a b c", "b c d", "a b c d", false},
+        {"한쪽 삭제 + 다른 줄 변경", "a b c d", "a c d", "a b c Y", "a c Y", false},
+        {"빈 base 에 같은 내용", "", "a b", "a b", "a b", false},
+        {"같은 줄을 다르게 변경", "a b c", "a X c", "a Y c",
+            "a <<<<<<< 내 변경 X ======= Y >>>>>>> 디스크의 현재 파일 c", true},
+        {"빈 base 에 다른 내용", "", "a", "b",
+            "<<<<<<< 내 변경 a ======= b >>>>>>> 디스크의 현재 파일", true},
+        {"한쪽 삭제 + 같은 줄 변경", "a b c", "a c", "a Y c",
+            "a <<<<<<< 내 변경 ======= Y >>>>>>> 디스크의 현재 파일 c", true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, conflict := merge3(lines(tt.base), lines(tt.mine), lines(tt.their))
+            // 충돌 표시에 공백이 있으므로 공백 기준으로 펼쳐 비교
+            if g := strings.Join(strings.Fields(strings.Join(got, " ")), " "); g != tt.want {
+                t.Errorf("merge3 = %q, want %q", g, tt.want)
+            }
+            if conflict != tt.conflict {
+                t.Errorf("conflict = %v, want %v", conflict, tt.conflict)
+            }
+        })
+    }
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// ------------------------------------------------------
+// 23. 적용 후 헬스 확인 및 자동 롤백
+// ------------------------------------------------------
+
+// 적용 후 상태를 지켜보는 시간 (.env 의 health_window, 초 단위. 0 이면 자동 롤백 안 함)
+var healthWindow = 60 * time.Second
+
+// ps 조회 간격 (테스트에서 줄인다)
+var healthPollInterval = 3 * time.Second
+
+// loadHealthWindow: .env 의 health_window 반영
+func loadHealthWindow() {
+    v := os.Getenv("health_window")
+    if v == "" {
+        return
+    }
+    n, err := strconv.Atoi(v)
+    if err != nil || n < 0 {
+        log.Printf("[경고] health_window 값이 잘못되었습니다(%q). 기본값 %v 사용", v, healthWindow)
+        return
+    }
+    healthWindow = time.Duration(n) * time.Second
+}
+
+// unhealthyServices: 문제가 있는 서비스 설명 목록
+// 종료 코드 0 으로 끝난 서비스(초기화용 one-shot 등)는 정상으로 본다.
+// created 는 up 이 컨테이너를 만들었지만 시작하지 못한 상태(의존 서비스 실패 등)다.
+func unhealthyServices(services []serviceStatus) []string {
+    var bad []string
+    for _, s := range services {
+        switch {
+        case s.State == "missing":
+            bad = append(bad, s.Service+": 컨테이너 없음")
+        case s.State == "created":
+            bad = append(bad, s.Service+": 시작되지 않음 (created)")
+        case s.State == "exited" && s.ExitCode != 0, s.State == "dead":
+            bad = append(bad, fmt.Sprintf("%s: %s (exit %d)", s.Service, s.State, s.ExitCode))
+        case s.State == "restarting":
+            bad = append(bad, s.Service+": 재시작 반복 중")
+        case s.Health == "unhealthy":
+            bad = append(bad, s.Service+": unhealthy")
+        }
+    }
+    return bad
+}
+
+// waitHealthy: window 동안 ps 로 상태를 확인해 문제가 보이면 즉시 오류 반환
+// 모든 서비스가 준비되면(실행 중이고 헬스체크가 없거나 healthy, 또는 종료 코드 0 으로 끝남) 일찍 끝내고,
+// 그렇지 않으면 window 가 끝날 때 헬스체크가 아직 starting 인 서비스가 없어야 성공이다.
+func waitHealthy(w io.Writer, filePath string, window time.Duration) error {
+    fmt.Fprintf(w, "\n[헬스 확인] %v 동안 서비스 상태를 확인합니다.\n", window)
+    deadline := time.Now().Add(window)
+    for {
+        services, err := composeStatus(filePath)
+        if err != nil {
+            return fmt.Errorf("상태 조회 실패: %v", err)
+        }
+        if bad := unhealthyServices(services); len(bad) > 0 {
+            return fmt.Errorf("비정상 서비스: %s", strings.Join(bad, ", "))
+        }
+        allReady, starting := len(services) > 0, []string{}
+        for _, s := range services {
+            if !serviceReady(s) {
+                allReady = false
+            }
+            if s.Health == "starting" {
+                starting = append(starting, s.Service)
+            }
+        }
+        if allReady {
+            fmt.Fprintf(w, "[헬스 확인] 모든 서비스 준비됨 (상태: %s)\n", summarizeStatus(services))
+            return nil
+        }
+        if !time.Now().Before(deadline) {
+            if len(starting) > 0 {
+                return fmt.Errorf("%v 안에 healthy 가 되지 않은 서비스: %s", window, strings.Join(starting, ", "))
+            }
+            fmt.Fprintf(w, "[헬스 확인] %v 동안 이상 없음 (상태: %s)\n", window, summarizeStatus(services))
+            return nil
+        }
+        time.Sleep(healthPollInterval)
+    }
+}
+
+// serviceReady: 더 기다릴 필요가 없는 서비스인지 (unhealthyServices 를 통과한 서비스에 대해)
+func serviceReady(s serviceStatus) bool {
+    switch s.State {
+    case "running":
+        return s.Health == "" || s.Health == "healthy"
+    case "exited":
+        return s.ExitCode == 0
+    }
+    return false
+}
+
+// restoreAndApply: data 로 파일을 되돌려 이력을 남기고 다시 적용 (롤백 작업과 자동 롤백에서 사용)
+func restoreAndApply(w io.Writer, fullPath string, data []byte, meta revisionMeta, opts applyOptions) (string, error) {
+    revID, err := revisions.Commit(fullPath, data, meta)
+    if err != nil {
+        return "", fmt.Errorf("롤백 실패: %v", err)
+    }
+    fmt.Fprintf(w, "롤백 저장 완료 (리비전 %s)\n", revID)
+    err = applyCompose(w, fullPath, opts)
+    recordRestartResult(fullPath, revID, restartResultText(err))
+    return revID, err
+}
+
+// applyWithAutoRollback: 적용 후 스택이 정상 기동하지 않으면 previous 내용으로 자동 롤백
+// (헬스 확인이 꺼져 있으면 적용만 한다. 사전 pull 실패 시 파일 복원은 항상 한다). 두 번의 시도 결과는 작업 출력과 리비전 이력에 남는다.
+// 자동 롤백을 했다면 그 리비전 ID 를 함께 반환한다.
+func applyWithAutoRollback(w io.Writer, fullPath, revID string, previous []byte, author string, opts applyOptions) (string, error) {
+    err := applyCompose(w, fullPath, opts)
+    // 사전 pull 실패는 down 전에 중단했으므로 스택은 그대로다. 다시 적용(down/up)하지 않고 파일만 되돌린다.
+    // 헬스 확인이 꺼져 있어도 저장한 파일과 실행 중인 스택이 어긋나지 않도록 항상 되돌린다.
+    if errors.Is(err, errPullFailed) {
+        recordRestartResult(fullPath, revID, restartResultText(err)+" (down 전에 중단, 파일만 복원)")
+        fmt.Fprintf(w, "\n[자동 롤백] down 전에 중단: %v\n[자동 롤백] 실행 중인 스택은 그대로 두고 파일만 저장 전 내용으로 되돌립니다.\n", err)
+        meta := revisionMeta{
+            Author:  author,
+            Message: fmt.Sprintf("자동 롤백: 이미지 pull 실패로 down 전에 중단 (%v)", err),
+            Action:  "auto-rollback",
+        }
+        rbID, rbErr := revisions.Commit(fullPath, previous, meta)
+        if rbErr != nil {
+            return "", fmt.Errorf("down 전에 중단: %v / 파일 복원도 실패: %v", err, rbErr)
+        }
+        fmt.Fprintf(w, "파일 복원 완료 (리비전 %s)\n", rbID)
+        return rbID, fmt.Errorf("down 전에 중단: %v / 파일은 저장 전 내용으로 복원 (리비전 %s, 재적용 안 함)", err, rbID)
+    }
+    if err == nil && healthWindow > 0 {
+        err = waitHealthy(w, fullPath, healthWindow)
+    }
+    if err == nil || healthWindow <= 0 {
+        recordRestartResult(fullPath, revID, restartResultText(err))
+        return "", err
+    }
+
+    recordRestartResult(fullPath, revID, restartResultText(err)+" (자동 롤백됨)")
+
+    fmt.Fprintf(w, "\n[자동 롤백] 1차 적용 실패: %v\n[자동 롤백] 저장 전 내용으로 되돌리고 다시 적용합니다.\n", err)
+    meta := revisionMeta{
+        Author:  author,
+        Message: fmt.Sprintf("자동 롤백: 적용 후 스택이 정상 기동하지 않음 (%v)", err),
+        Action:  "auto-rollback",
+        Restart: true,
+    }
+    rbID, rbErr := restoreAndApply(w, fullPath, previous, meta, opts)
+    if rbErr == nil {
+        rbErr = waitHealthy(w, fullPath, healthWindow)
+        if rbErr != nil {
+            recordRestartResult(fullPath, rbID, restartResultText(rbErr))
+        }
+    }
+    if rbErr != nil {
+        return rbID, fmt.Errorf("1차 적용 실패: %v / 자동 롤백도 실패: %v", err, rbErr)
+    }
+    return rbID, fmt.Errorf("1차 적용 실패: %v / 이전 버전으로 자동 롤백 완료 (리비전 %s)", err, rbID)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "runtime"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestUnhealthyServices(t *testing.T) {
+    tests := []struct {
+        name string
+        s    serviceStatus
+        bad  bool
+    }{
+        {"실행 중", serviceStatus{Service: "web", State: "running"}, false},
+        {"healthy", serviceStatus{Service: "web", State: "running", Health: "healthy"}, false},
+        {"starting 은 아직 기다림", serviceStatus{Service: "web", State: "running", Health: "starting"}, false},
+        {"one-shot 정상 종료", serviceStatus{Service: "init", State: "exited", ExitCode: 0}, false},
+        {"컨테이너 없음", serviceStatus{Service: "web", State: "missing"}, true},
+        {"시작되지 않음", serviceStatus{Service: "web", State: "created"}, true},
+        {"오류 종료", serviceStatus{Service: "db", State: "exited", ExitCode: 1}, true},
+        {"dead", serviceStatus{Service: "db", State: "dead"}, true},
+        {"재시작 반복", serviceStatus{Service: "web", State: "restarting", ExitCode: 1}, true},
+        {"unhealthy", serviceStatus{Service: "web", State: "running", Health: "unhealthy"}, true},
+    }
+    for _, tt := range tests {
+        bad := unhealthyServices([]serviceStatus{tt.s})
+        if (len(bad) > 0) != tt.bad {
+            t.Errorf("%s: unhealthyServices = %v, want bad=%v", tt.name, bad, tt.bad)
+        }
+        if len(bad) > 0 && !strings.HasPrefix(bad[0], tt.s.Service+": ") {
+            t.Errorf("%s: 설명에 서비스 이름이 없음: %s", tt.name, bad[0])
+        }
+    }
+}
+
+// fakeComposePs: ps 를 부를 때마다 steps 의 출력을 차례로 내는 가짜 compose 명령을 composeCommand 로 설정한다.
+// 마지막 출력은 그 뒤로 계속 반복하고, config --services 는 services 를 출력한다. compose 파일 경로를 돌려준다.
+func fakeComposePs(t *testing.T, services []string, steps ...string) string {
+    if runtime.GOOS == "windows" {
+        t.Skip("sh 스크립트 필요")
+    }
+    dir := t.TempDir()
+    write := func(name, data string, mode os.FileMode) {
+        if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), mode); err != nil {
+            t.Fatal(err)
+        }
+    }
+    // 인자: -f <파일> <명령> ...
+    write("compose", `#!/bin/sh
+case "$3" in
+ps)
+    n=$(($(cat count 2>/dev/null || echo 0) + 1))
+    echo $n > count
+    if [ -f ps.$n.json ]; then cat ps.$n.json; else cat ps.last.json; fi ;;
+config)
+    cat services ;;
+esac
+`, 0755)
+    for i, out := range steps {
+        write(fmt.Sprintf("ps.%d.json", i+1), out, 0644)
+    }
+    write("ps.last.json", steps[len(steps)-1], 0644)
+    write("services", strings.Join(services, "\n")+"\n", 0644)
+    write("docker-compose.yml", "services: {}\n", 0644)
+
+    oldCommand, oldInterval := composeCommand, healthPollInterval
+    composeCommand = filepath.Join(dir, "compose")
+    healthPollInterval = 10 * time.Millisecond
+    t.Cleanup(func() { composeCommand, healthPollInterval = oldCommand, oldInterval })
+    return filepath.Join(dir, "docker-compose.yml")
+}
+
+func TestWaitHealthy(t *testing.T) {
+    const (
+        webRunning  = `{"Service":"web","State":"running","Health":""}`
+        webStarting = `{"Service":"web","State":"running","Health":"starting"}`
+        webHealthy  = `{"Service":"web","State":"running","Health":"healthy"}`
+        webCreated  = `{"Service":"web","State":"created","Health":""}`
+        dbRunning   = `{"Service":"db","State":"running","Health":""}`
+        dbExited    = `{"Service":"db","State":"exited","ExitCode":1}`
+        initDone    = `{"Service":"init","State":"exited","ExitCode":0}`
+    )
+    lines := func(entries ...string) string { return strings.Join(entries, "\n") + "\n" }
+
+    tests := []struct {
+        name     string
+        services []string // config --services
+        steps    []string // 차례로 나오는 ps 출력
+        window   time.Duration
+        wantErr  string
+        maxPolls int // 이 횟수 안에 끝나야 한다
+    }{
+        {"헬스체크 없이 모두 실행 중이면 바로 끝남", []string{"web", "db"},
+            []string{lines(webRunning, dbRunning)}, time.Hour, "", 1},
+        {"헬스체크 있는 서비스와 섞여도 healthy 면 바로 끝남", []string{"web", "db"},
+            []string{lines(webHealthy, dbRunning)}, time.Hour, "", 1},
+        {"JSON 배열 출력", []string{"web", "db"},
+            []string{"[" + webRunning + "," + dbRunning + "]"}, time.Hour, "", 1},
+        {"one-shot 정상 종료는 준비된 것으로 봄", []string{"web", "init"},
+            []string{lines(webRunning, initDone)}, time.Hour, "", 1},
+        {"starting 이 healthy 가 될 때까지 기다림", []string{"web", "db"},
+            []string{lines(webStarting, dbRunning), lines(webStarting, dbRunning), lines(webHealthy, dbRunning)}, time.Hour, "", 3},
+        {"window 안에 healthy 가 되지 않음", []string{"web"},
+            []string{lines(webStarting)}, 50 * time.Millisecond, "healthy 가 되지 않은 서비스: web", 0},
+        {"created 는 비정상", []string{"web", "db"},
+            []string{lines(webCreated, dbRunning)}, time.Hour, "web: 시작되지 않음", 1},
+        {"기다리는 중 오류 종료", []string{"web", "db"},
+            []string{lines(webStarting, dbRunning), lines(webStarting, dbExited)}, time.Hour, "db: exited (exit 1)", 2},
+        {"컨테이너 없는 서비스", []string{"web", "db"},
+            []string{lines(webRunning)}, time.Hour, "db: 컨테이너 없음", 1},
+        {"컨테이너가 하나도 없음", nil,
+            []string{""}, 50 * time.Millisecond, "", 0},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            file := fakeComposePs(t, tt.services, tt.steps...)
+            var out bytes.Buffer
+            err := waitHealthy(&out, file, tt.window)
+            if tt.wantErr == "" && err != nil {
+                t.Fatalf("err = %v\n%s", err, out.String())
+            }
+            if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+                t.Fatalf("err = %v, want %q", err, tt.wantErr)
+            }
+            if tt.maxPolls > 0 {
+                data, _ := ioutil.ReadFile(filepath.Join(filepath.Dir(file), "count"))
+                var polls int
+                fmt.Sscan(string(data), &polls)
+                if polls > tt.maxPolls {
+                    t.Fatalf("ps %d번 호출, 최대 %d번이어야 함", polls, tt.maxPolls)
+                }
+            }
+        })
+    }
+}
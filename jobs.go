@@ -0,0 +1,395 @@
+package main
+
+import (
+    "bytes"
+    "crypto/rand"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "path/filepath"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ------------------------------------------------------
+// 21. 백그라운드 작업 (재시작/롤백/pull 등 오래 걸리는 compose 작업)
+// ------------------------------------------------------
+
+// 작업 상태
+const (
+    jobQueued    = "queued"
+    jobRunning   = "running"
+    jobSucceeded = "succeeded"
+    jobFailed    = "failed"
+)
+
+// 메모리에 보관하는 최대 작업 수 (초과 시 끝난 작업부터 정리)
+const maxJobs = 200
+
+type job struct {
+    ID        string
+    Kind      string // restart, save+restart, rollback, service:pull ...
+    Path      string // 웹 경로 (예: testtt/aaa.yml)
+    Project   string // 프로젝트 키 (같은 키의 작업은 순서대로 하나씩 실행)
+    Initiator string // 요청한 사용자 이메일
+    Created   time.Time
+    After     string        // 앞서 대기/실행 중이던 같은 프로젝트의 작업 ID (없으면 "")
+    doneCh    chan struct{} // 작업 종료 시 close
+
+    mu       sync.Mutex
+    status   string
+    err      string
+    started  time.Time
+    finished time.Time
+    output   bytes.Buffer
+    notify   chan struct{} // 출력/상태가 바뀔 때마다 close 후 새로 생성
+}
+
+// 작업 조회 응답
+type jobView struct {
+    ID         string    `json:"id"`
+    Kind       string    `json:"kind"`
+    Path       string    `json:"path"`
+    Initiator  string    `json:"initiator"`
+    Status     string    `json:"status"`
+    After      string    `json:"queuedBehind,omitempty"`
+    Error      string    `json:"error,omitempty"`
+    Created    time.Time `json:"created"`
+    Started    time.Time `json:"started,omitempty"`
+    Finished   time.Time `json:"finished,omitempty"`
+    DurationMs int64     `json:"durationMs"`
+    Output     string    `json:"output,omitempty"`
+}
+
+// Write: 작업 출력 추가 (io.Writer)
+func (j *job) Write(p []byte) (int, error) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    n, err := j.output.Write(p)
+    j.signal()
+    return n, err
+}
+
+// signal: 대기 중인 스트림에 변경 알림 (j.mu 를 잡은 상태에서 호출)
+func (j *job) signal() {
+    close(j.notify)
+    j.notify = make(chan struct{})
+}
+
+func (j *job) setStatus(status string, err error) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    j.status = status
+    switch status {
+    case jobRunning:
+        j.started = time.Now()
+    case jobSucceeded, jobFailed:
+        j.finished = time.Now()
+        if err != nil {
+            j.err = err.Error()
+        }
+        close(j.doneCh)
+    }
+    j.signal()
+}
+
+func (j *job) done() bool {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    return j.status == jobSucceeded || j.status == jobFailed
+}
+
+// view: 현재 상태 스냅샷 (withOutput 이면 전체 출력 포함)
+func (j *job) view(withOutput bool) jobView {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    v := jobView{
+        ID:        j.ID,
+        Kind:      j.Kind,
+        Path:      j.Path,
+        Initiator: j.Initiator,
+        Status:    j.status,
+        Error:     j.err,
+        Created:   j.Created,
+        Started:   j.started,
+        Finished:  j.finished,
+        After:     j.After,
+    }
+    if !j.started.IsZero() {
+        end := j.finished
+        if end.IsZero() {
+            end = time.Now()
+        }
+        v.DurationMs = end.Sub(j.started).Milliseconds()
+    }
+    if withOutput {
+        v.Output = j.output.String()
+    }
+    return v
+}
+
+// outputFrom: offset 이후의 출력, 변경 알림 채널, 종료 여부
+func (j *job) outputFrom(offset int) ([]byte, chan struct{}, bool) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    data := append([]byte{}, j.output.Bytes()[offset:]...)
+    finished := j.status == jobSucceeded || j.status == jobFailed
+    return data, j.notify, finished
+}
+
+// 작업 관리자
+type jobManager struct {
+    mu    sync.Mutex
+    jobs  map[string]*job
+    locks map[string]*projectLock
+}
+
+// 프로젝트별 잠금: 변경 작업(저장+재시작, 재시작, 롤백 등)은 프로젝트마다 하나씩만 실행
+// 쓰는 작업이 없어지면(refs == 0) locks 에서 지운다.
+type projectLock struct {
+    sem  chan struct{} // 실행 권한 (용량 1)
+    tail *job          // 마지막으로 등록된 작업
+    refs int           // 이 잠금을 쓰는 작업 수 (대기/실행 중인 작업 + tryLock 으로 잡은 것)
+}
+
+var jobs = &jobManager{jobs: make(map[string]*job), locks: make(map[string]*projectLock)}
+
+// projectKey: compose 프로젝트 식별자 (compose 파일이 있는 디렉토리)
+func projectKey(fullPath string) string {
+    return filepath.Dir(fullPath)
+}
+
+// lockFor: 프로젝트 잠금 (m.mu 를 잡은 상태에서 호출)
+func (m *jobManager) lockFor(project string) *projectLock {
+    l, ok := m.locks[project]
+    if !ok {
+        l = &projectLock{sem: make(chan struct{}, 1)}
+        m.locks[project] = l
+    }
+    return l
+}
+
+// unlock: 실행 권한을 돌려주고, 더 쓰는 작업이 없으면 잠금을 지운다
+func (m *jobManager) unlock(project string, l *projectLock) {
+    <-l.sem
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    l.refs--
+    if l.refs == 0 && m.locks[project] == l {
+        delete(m.locks, project)
+    }
+}
+
+// tryLock: 대기/실행 중인 작업이 없을 때만 즉시 잠금 (동기식 저장 등에 사용)
+// 잠금에 실패하면 release 는 nil 이고 busy 에 진행 중인 작업을 돌려준다.
+func (m *jobManager) tryLock(project string) (release func(), busy *job) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    l := m.lockFor(project)
+    if l.tail != nil && !l.tail.done() {
+        return nil, l.tail
+    }
+    select {
+    case l.sem <- struct{}{}:
+        l.refs++
+        return func() { m.unlock(project, l) }, nil
+    default:
+        // 다른 동기식 작업이 잠시 잡고 있는 경우
+        return nil, nil
+    }
+}
+
+func newJobID() string {
+    b := make([]byte, 6)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
+
+// submit: 작업을 등록하고 백그라운드에서 fn 실행. fn 의 출력은 w 로 기록한다.
+// 같은 프로젝트의 앞선 작업이 있으면 끝날 때까지 대기 후 실행한다 (j.After).
+func (m *jobManager) submit(kind, path, project, initiator string, fn func(w io.Writer) error) *job {
+    j := &job{
+        ID:        newJobID(),
+        Kind:      kind,
+        Path:      path,
+        Project:   project,
+        Initiator: initiator,
+        Created:   time.Now(),
+        doneCh:    make(chan struct{}),
+        status:    jobQueued,
+        notify:    make(chan struct{}),
+    }
+    m.mu.Lock()
+    lock := m.lockFor(project)
+    var ahead *job
+    if lock.tail != nil && !lock.tail.done() {
+        ahead = lock.tail
+        j.After = ahead.ID
+    }
+    lock.tail = j
+    lock.refs++
+    m.jobs[j.ID] = j
+    m.prune()
+    m.mu.Unlock()
+
+    go func() {
+        if ahead != nil {
+            fmt.Fprintf(j, "[대기] 같은 프로젝트의 작업 %s 가 끝나기를 기다립니다.\n", ahead.ID)
+            <-ahead.doneCh
+            ahead = nil
+        }
+        lock.sem <- struct{}{}
+        defer m.unlock(project, lock)
+
+        j.setStatus(jobRunning, nil)
+        fmt.Fprintf(j, "[%s] %s 작업 시작 (%s, 요청자: %s)\n", j.started.Format("2006-01-02 15:04:05"), j.Kind, j.Path, j.Initiator)
+        err := fn(j)
+        if err != nil {
+            fmt.Fprintf(j, "\n[작업 실패] %v\n", err)
+            j.setStatus(jobFailed, err)
+            log.Printf("[작업 %s] %s 실패: %v", j.ID, j.Kind, err)
+            return
+        }
+        fmt.Fprintf(j, "\n[작업 완료]\n")
+        j.setStatus(jobSucceeded, nil)
+    }()
+    return j
+}
+
+// jobID: submit 에 넘긴 fn 안에서 현재 작업 ID 확인 (fn 의 w 는 작업 자신)
+func jobID(w io.Writer) string {
+    if j, ok := w.(*job); ok {
+        return j.ID
+    }
+    return ""
+}
+
+func (m *jobManager) get(id string) *job {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return m.jobs[id]
+}
+
+// list: 최근 작업부터
+func (m *jobManager) list() []*job {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    result := make([]*job, 0, len(m.jobs))
+    for _, j := range m.jobs {
+        result = append(result, j)
+    }
+    sort.Slice(result, func(i, k int) bool {
+        return result[i].Created.After(result[k].Created)
+    })
+    return result
+}
+
+// prune: 보관 개수를 넘으면 끝난 작업 중 오래된 것부터 제거 (m.mu 를 잡은 상태에서 호출)
+func (m *jobManager) prune() {
+    if len(m.jobs) <= maxJobs {
+        return
+    }
+    var finished []*job
+    for _, j := range m.jobs {
+        if j.done() {
+            finished = append(finished, j)
+        }
+    }
+    sort.Slice(finished, func(i, k int) bool {
+        return finished[i].Created.Before(finished[k].Created)
+    })
+    for _, j := range finished {
+        if len(m.jobs) <= maxJobs {
+            break
+        }
+        delete(m.jobs, j.ID)
+    }
+}
+
+// respondJob: 작업 ID 를 헤더(X-Job-ID)와 메시지에 담아 202 응답
+func respondJob(c *gin.Context, j *job, msg string) {
+    c.Header("X-Job-ID", j.ID)
+    if j.After != "" {
+        msg = fmt.Sprintf("%s\n같은 프로젝트의 작업 %s 가 진행 중이라 그 뒤에 대기합니다.", msg, j.After)
+    }
+    c.String(http.StatusAccepted, fmt.Sprintf("%s\n작업 ID: %s", msg, j.ID))
+}
+
+// respondBusy: 동기식 변경 작업을 진행할 수 없을 때 423 응답
+func respondBusy(c *gin.Context, busy *job) {
+    if busy != nil {
+        c.Header("X-Job-ID", busy.ID)
+        c.String(http.StatusLocked, fmt.Sprintf("같은 프로젝트의 작업 %s (%s) 가 진행 중입니다. 끝난 뒤 다시 시도하세요.", busy.ID, busy.Kind))
+        return
+    }
+    c.String(http.StatusLocked, "같은 프로젝트의 다른 작업이 진행 중입니다. 잠시 후 다시 시도하세요.")
+}
+
+// 작업 목록 (AJAX)
+func listJobsAPI(c *gin.Context) {
+    result := []jobView{}
+    for _, j := range jobs.list() {
+        if canAccess(c, j.Project, permRead) {
+            result = append(result, j.view(false))
+        }
+    }
+    c.JSON(http.StatusOK, result)
+}
+
+// 작업 상세 (출력 포함)
+func getJobAPI(c *gin.Context) {
+    j := jobs.get(c.Param("id"))
+    if j == nil || !canAccess(c, j.Project, permRead) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "작업을 찾을 수 없습니다."})
+        return
+    }
+    c.JSON(http.StatusOK, j.view(true))
+}
+
+// 작업 출력 스트리밍 (SSE): 지금까지의 출력을 먼저 보내고 이후 출력을 줄 단위로 전달
+func streamJobAPI(c *gin.Context) {
+    j := jobs.get(c.Param("id"))
+    if j == nil || !canAccess(c, j.Project, permRead) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "작업을 찾을 수 없습니다."})
+        return
+    }
+    ctx := c.Request.Context()
+    offset := 0
+    c.Header("Cache-Control", "no-cache")
+    c.Header("X-Accel-Buffering", "no")
+    c.Stream(func(w io.Writer) bool {
+        data, notify, finished := j.outputFrom(offset)
+        // 완성된 줄까지만 전송 (작업이 끝났으면 남은 것 모두)
+        if !finished {
+            if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+                data = data[:i+1]
+            } else {
+                data = nil
+            }
+        }
+        if len(data) > 0 {
+            offset += len(data)
+            for _, line := range bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) {
+                c.SSEvent("output", string(line))
+            }
+        }
+        if finished {
+            c.SSEvent("end", j.view(false).Status)
+            return false
+        }
+        if len(data) > 0 {
+            return true
+        }
+        select {
+        case <-notify:
+            return true
+        case <-ctx.Done():
+            return false
+        }
+    })
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+func newTestJobManager() *jobManager {
+    return &jobManager{jobs: make(map[string]*job), locks: make(map[string]*projectLock)}
+}
+
+// waitDone: 작업이 끝나기를 기다린다
+func waitDone(t *testing.T, j *job) {
+    t.Helper()
+    select {
+    case <-j.doneCh:
+    case <-time.After(5 * time.Second):
+        t.Fatalf("작업 %s 가 끝나지 않음", j.ID)
+    }
+}
+
+// waitLocksReleased: 모든 프로젝트 잠금이 지워지기를 기다린다 (작업 종료 후 잠금 해제는 조금 늦을 수 있다)
+func waitLocksReleased(t *testing.T, m *jobManager) {
+    t.Helper()
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        m.mu.Lock()
+        n := len(m.locks)
+        m.mu.Unlock()
+        if n == 0 {
+            return
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("남은 프로젝트 잠금 %d개", n)
+        }
+        time.Sleep(time.Millisecond)
+    }
+}
+
+func TestTryLock(t *testing.T) {
+    m := newTestJobManager()
+
+    release, busy := m.tryLock("p")
+    if release == nil || busy != nil {
+        t.Fatal("비어 있는 프로젝트는 잠글 수 있어야 함")
+    }
+    // 동기식 작업끼리: 진행 중인 작업 없이 거부
+    if r2, busy := m.tryLock("p"); r2 != nil || busy != nil {
+        t.Fatalf("이미 잡힌 잠금: release=%v busy=%v", r2 != nil, busy)
+    }
+    // 다른 프로젝트는 영향 없음
+    other, _ := m.tryLock("q")
+    if other == nil {
+        t.Fatal("다른 프로젝트는 잠글 수 있어야 함")
+    }
+    other()
+    release()
+    if len(m.locks) != 0 {
+        t.Fatalf("해제 후 남은 잠금 %d개", len(m.locks))
+    }
+
+    // 대기/실행 중인 작업이 있으면 그 작업을 돌려준다
+    gate := make(chan struct{})
+    j := m.submit("restart", "p/docker-compose.yml", "p", "user@test", func(w io.Writer) error {
+        <-gate
+        return nil
+    })
+    queued := m.submit("rollback", "p/docker-compose.yml", "p", "user@test", func(w io.Writer) error { return nil })
+    if r, busy := m.tryLock("p"); r != nil || busy != queued {
+        t.Fatalf("busy = %v, want 마지막 대기 작업 %s", busy, queued.ID)
+    }
+    close(gate)
+    waitDone(t, j)
+    waitDone(t, queued)
+    waitLocksReleased(t, m)
+
+    release, busy = m.tryLock("p")
+    if release == nil {
+        t.Fatalf("작업이 끝난 뒤에는 잠글 수 있어야 함 (busy=%v)", busy)
+    }
+    release()
+    if len(m.locks) != 0 {
+        t.Fatal("잠금이 남음")
+    }
+}
+
+func TestSubmitFIFO(t *testing.T) {
+    m := newTestJobManager()
+    const n = 10
+    gate := make(chan struct{})
+    var (
+        mu      sync.Mutex
+        order   []int
+        running int
+    )
+    var list []*job
+    for i := 0; i < n; i++ {
+        i := i
+        list = append(list, m.submit("restart", "p", "p", "user@test", func(w io.Writer) error {
+            mu.Lock()
+            running++
+            if running > 1 {
+                t.Errorf("같은 프로젝트 작업이 동시에 %d개 실행됨", running)
+            }
+            order = append(order, i)
+            mu.Unlock()
+            if i == 0 {
+                <-gate
+            }
+            time.Sleep(time.Millisecond)
+            mu.Lock()
+            running--
+            mu.Unlock()
+            if i%3 == 1 {
+                return fmt.Errorf("실패 %d", i) // 앞 작업이 실패해도 다음 작업은 실행
+            }
+            return nil
+        }))
+    }
+    // 등록 순서대로 앞 작업 뒤에 대기
+    for i, j := range list {
+        want := ""
+        if i > 0 {
+            want = list[i-1].ID
+        }
+        if j.After != want {
+            t.Errorf("작업 %d: After = %q, want %q", i, j.After, want)
+        }
+    }
+
+    // 다른 프로젝트는 앞 프로젝트를 기다리지 않는다
+    other := m.submit("restart", "q", "q", "user@test", func(w io.Writer) error { return nil })
+    waitDone(t, other)
+    if other.After != "" {
+        t.Fatalf("다른 프로젝트 작업이 대기함: %s", other.After)
+    }
+
+    close(gate)
+    for _, j := range list {
+        waitDone(t, j)
+    }
+    for i, got := range order {
+        if got != i {
+            t.Fatalf("실행 순서 %v", order)
+        }
+    }
+    if len(order) != n {
+        t.Fatalf("실행된 작업 %d개, want %d", len(order), n)
+    }
+    if v := list[1].view(true); v.Status != jobFailed || !strings.Contains(v.Output, "[대기] 같은 프로젝트의 작업 "+list[0].ID) {
+        t.Fatalf("작업 1: %+v", v)
+    }
+    waitLocksReleased(t, m)
+}
+
+func TestSaveBusy(t *testing.T) {
+    setupRBACTree(t)
+    setTestUsers(t, &User{Email: "admin@test", Role: roleAdmin})
+    oldJobs, oldAudit := jobs, auditFile
+    jobs = newTestJobManager()
+    auditFile = filepath.Join(t.TempDir(), "audit.log")
+    t.Cleanup(func() { jobs, auditFile = oldJobs, oldAudit })
+
+    r := testRouter()
+    r.POST("/console/api/file", saveFileAPI)
+    r.PUT("/api/v1/file", apiSaveFile)
+    project := projectKey(filepath.Join(baseDir, "payments", "docker-compose.yml"))
+    form := url.Values{"path": {"payments/docker-compose.yml"}, "content": {"services: {}\n"}, "etag": {`"stale"`}}
+    body := `{"path":"payments/docker-compose.yml","content":"services: {}\n","etag":"\"stale\""}`
+    putJSON := func() *httptest.ResponseRecorder {
+        req := httptest.NewRequest(http.MethodPut, "/api/v1/file", strings.NewReader(body))
+        req.Header.Set("Content-Type", "application/json")
+        req.Header.Set(testUserHeader, "admin@test")
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        return w
+    }
+    header := map[string]string{testUserHeader: "admin@test"}
+
+    // 진행 중인 작업이 있으면 423 과 그 작업 ID
+    gate := make(chan struct{})
+    j := jobs.submit("restart", "payments/docker-compose.yml", project, "admin@test", func(w io.Writer) error {
+        <-gate
+        return nil
+    })
+    w := testRequest(r, http.MethodPost, "/console/api/file", form, header)
+    if w.Code != http.StatusLocked || w.Header().Get("X-Job-ID") != j.ID || !strings.Contains(w.Body.String(), j.ID) {
+        t.Fatalf("콘솔: %d %s %s", w.Code, w.Header().Get("X-Job-ID"), w.Body.String())
+    }
+    w = putJSON()
+    var resp struct {
+        Error apiErrorBody `json:"error"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusLocked ||
+        resp.Error.Code != apiErrProjectBusy || w.Header().Get("X-Job-ID") != j.ID {
+        t.Fatalf("v1: %d %s (%v)", w.Code, w.Body.String(), err)
+    }
+    close(gate)
+    waitDone(t, j)
+    waitLocksReleased(t, jobs)
+
+    // 다른 동기식 저장이 잠금을 잡고 있으면 작업 ID 없이 423
+    release, _ := jobs.tryLock(project)
+    if release == nil {
+        t.Fatal("잠금 실패")
+    }
+    w = testRequest(r, http.MethodPost, "/console/api/file", form, header)
+    if w.Code != http.StatusLocked || w.Header().Get("X-Job-ID") != "" {
+        t.Fatalf("동기식 잠금 중: %d %s", w.Code, w.Body.String())
+    }
+    release()
+
+    // 잠금이 풀리면 그다음 검사(etag)로 넘어간다
+    w = testRequest(r, http.MethodPost, "/console/api/file", form, header)
+    if w.Code == http.StatusLocked {
+        t.Fatalf("잠금이 풀린 뒤에도 423: %s", w.Body.String())
+    }
+    waitLocksReleased(t, jobs)
+}
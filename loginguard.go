@@ -0,0 +1,262 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "golang.org/x/crypto/bcrypt"
+)
+
+// ------------------------------------------------------
+// 30. 로그인 시도 제한 및 계정 잠금
+// ------------------------------------------------------
+
+// 로그인 실패 시 응답 (이메일 존재 여부가 드러나지 않도록 항상 같은 문구)
+const loginFailedMessage = "이메일 또는 비밀번호가 올바르지 않습니다."
+
+// 지연 없이 허용하는 실패 횟수. 이후에는 1초부터 두 배씩 기다려야 다시 시도할 수 있다.
+// IP 는 여러 사용자가 같은 주소를 쓸 수 있어 더 넉넉하게 둔다.
+const (
+    accountFreeFailures = 3
+    ipFreeFailures      = 10
+    maxLoginBackoff     = 5 * time.Minute
+)
+
+// 계정 잠금 (.env 의 login_max_failures, login_lockout(초)). 서버를 재시작하면 풀린다.
+var (
+    loginMaxFailures = 10
+    loginLockout     = 15 * time.Minute
+)
+
+// 기록을 지우는 기준 (마지막 실패 후 이 시간이 지나고 잠겨 있지 않으면 정리)
+const loginAttemptTTL = 24 * time.Hour
+
+// 계정/IP 별로 남기는 기록의 최대 개수. 넘으면 가장 오래된 기록부터 지운다.
+var loginAttemptLimit = 10000
+
+type loginAttempts struct {
+    Failures    int
+    LastFailure time.Time
+    LockedUntil time.Time
+}
+
+var (
+    loginMu         sync.Mutex
+    accountAttempts = make(map[string]*loginAttempts) // 키: 등록된 계정의 이메일
+    ipAttempts      = make(map[string]*loginAttempts) // 키: 접속 IP
+)
+
+// loadLoginGuard: .env 의 login_max_failures, login_lockout 반영
+func loadLoginGuard() {
+    if v := os.Getenv("login_max_failures"); v != "" {
+        if n, err := strconv.Atoi(v); err == nil && n > 0 {
+            loginMaxFailures = n
+        } else {
+            log.Printf("[경고] login_max_failures 값이 잘못되었습니다(%q). 기본값 %d 사용", v, loginMaxFailures)
+        }
+    }
+    if v := os.Getenv("login_lockout"); v != "" {
+        if n, err := strconv.Atoi(v); err == nil && n > 0 {
+            loginLockout = time.Duration(n) * time.Second
+        } else {
+            log.Printf("[경고] login_lockout 값이 잘못되었습니다(%q). 기본값 %v 사용", v, loginLockout)
+        }
+    }
+}
+
+// trustedProxies: .env 의 trusted_proxies (쉼표 구분 IP/CIDR). 없으면 nil 로 어떤 프록시도 믿지 않는다.
+// 믿지 않으면 X-Forwarded-For 를 무시하고 연결한 주소를 클라이언트 IP 로 쓴다.
+// (헤더를 바꿔 가며 IP 별 로그인 대기를 피하거나 감사 로그의 IP 를 속이는 것 방지)
+func trustedProxies() []string {
+    var result []string
+    for _, p := range strings.Split(os.Getenv("trusted_proxies"), ",") {
+        if p = strings.TrimSpace(p); p != "" {
+            result = append(result, p)
+        }
+    }
+    return result
+}
+
+// wait: 다음 시도까지 남은 시간 (free 번까지는 지연 없음)
+func (a *loginAttempts) wait(now time.Time, free int) time.Duration {
+    if a == nil {
+        return 0
+    }
+    if now.Before(a.LockedUntil) {
+        return a.LockedUntil.Sub(now)
+    }
+    if a.Failures <= free {
+        return 0
+    }
+    backoff := maxLoginBackoff
+    if shift := a.Failures - free - 1; shift < 20 {
+        if d := time.Second << uint(shift); d < backoff {
+            backoff = d
+        }
+    }
+    return a.LastFailure.Add(backoff).Sub(now)
+}
+
+// loginBlocked: 지금 로그인을 시도할 수 없으면 기다려야 할 시간과 계정 잠금 여부
+func loginBlocked(email, ip string) (time.Duration, bool) {
+    loginMu.Lock()
+    defer loginMu.Unlock()
+    now := time.Now()
+    if a := accountAttempts[email]; a != nil && now.Before(a.LockedUntil) {
+        return a.LockedUntil.Sub(now), true
+    }
+    wait := accountAttempts[email].wait(now, accountFreeFailures)
+    if w := ipAttempts[ip].wait(now, ipFreeFailures); w > wait {
+        wait = w
+    }
+    return wait, false
+}
+
+// loginFailed: 실패 기록. 이번 실패로 계정이 잠겼으면 true
+// 미등록 이메일은 계정 기록을 남기지 않는다. (아무 이메일이나 입력해 기록을 늘리는 것 방지, IP 제한은 그대로)
+func loginFailed(email, ip string) bool {
+    loginMu.Lock()
+    defer loginMu.Unlock()
+    now := time.Now()
+    pruneLoginAttempts(now)
+    ipAttempt := attemptsFor(ipAttempts, ip, now)
+    ipAttempt.Failures++
+    ipAttempt.LastFailure = now
+    if users[email] == nil {
+        return false
+    }
+    a := attemptsFor(accountAttempts, email, now)
+    a.Failures++
+    a.LastFailure = now
+    if a.Failures >= loginMaxFailures {
+        a.LockedUntil = now.Add(loginLockout)
+        a.Failures = 0
+        return true
+    }
+    return false
+}
+
+// attemptsFor: key 의 기록 (없으면 새로 만든다. loginAttemptLimit 에 닿으면 가장 오래된 기록을 지운다)
+func attemptsFor(m map[string]*loginAttempts, key string, now time.Time) *loginAttempts {
+    if a := m[key]; a != nil {
+        return a
+    }
+    if len(m) >= loginAttemptLimit {
+        evictOldestAttempt(m, now)
+    }
+    a := &loginAttempts{}
+    m[key] = a
+    return a
+}
+
+// evictOldestAttempt: 잠기지 않은 기록 중 마지막 실패가 가장 오래된 것을 지운다 (모두 잠겼으면 가장 먼저 풀리는 것)
+func evictOldestAttempt(m map[string]*loginAttempts, now time.Time) {
+    var oldest string
+    var oldestAt time.Time
+    found, oldestLocked := false, true
+    for key, a := range m {
+        locked := now.Before(a.LockedUntil)
+        at := a.LastFailure
+        if locked {
+            at = a.LockedUntil
+        }
+        if !found || (oldestLocked && !locked) || (oldestLocked == locked && at.Before(oldestAt)) {
+            oldest, oldestAt, oldestLocked, found = key, at, locked, true
+        }
+    }
+    if found {
+        delete(m, oldest)
+    }
+}
+
+// loginSucceeded: 성공하면 해당 계정과 IP 의 실패 기록 삭제
+func loginSucceeded(email, ip string) {
+    loginMu.Lock()
+    defer loginMu.Unlock()
+    delete(accountAttempts, email)
+    delete(ipAttempts, ip)
+}
+
+// clearAccountLock: 계정의 실패 기록과 잠금 삭제 (비밀번호 재설정을 마친 경우)
+func clearAccountLock(email string) {
+    loginMu.Lock()
+    defer loginMu.Unlock()
+    delete(accountAttempts, email)
+}
+
+// pruneLoginAttempts: 오래된 기록 정리 (loginMu 잠금 상태에서 호출)
+func pruneLoginAttempts(now time.Time) {
+    for _, m := range []map[string]*loginAttempts{accountAttempts, ipAttempts} {
+        for key, a := range m {
+            if now.Sub(a.LastFailure) > loginAttemptTTL && !now.Before(a.LockedUntil) {
+                delete(m, key)
+            }
+        }
+    }
+}
+
+// 잠긴 계정 (어드민 페이지 표시용)
+type lockedAccount struct {
+    Email       string
+    Registered  bool
+    LockedUntil time.Time
+}
+
+func lockedAccounts() []lockedAccount {
+    loginMu.Lock()
+    defer loginMu.Unlock()
+    now := time.Now()
+    var result []lockedAccount
+    for email, a := range accountAttempts {
+        if now.Before(a.LockedUntil) {
+            _, registered := users[email]
+            result = append(result, lockedAccount{Email: email, Registered: registered, LockedUntil: a.LockedUntil})
+        }
+    }
+    sort.Slice(result, func(i, j int) bool { return result[i].Email < result[j].Email })
+    return result
+}
+
+// 계정 잠금 해제 (어드민)
+func unlockAccount(c *gin.Context) {
+    email := c.PostForm("email")
+    ev := newAudit(c, "unlock", email)
+    loginMu.Lock()
+    _, ok := accountAttempts[email]
+    delete(accountAttempts, email)
+    loginMu.Unlock()
+    if !ok {
+        ev.record(errors.New("잠긴 계정 아님"))
+        c.String(http.StatusBadRequest, "잠긴 계정이 아닙니다.")
+        return
+    }
+    ev.record(nil)
+    c.String(http.StatusOK, fmt.Sprintf("%s 계정의 잠금을 해제했습니다. <a href='/console/admin'>돌아가기</a>", email))
+}
+
+// 미등록 이메일도 등록된 계정과 비슷한 시간이 걸리도록 비교에 쓰는 해시
+var (
+    dummyHashOnce sync.Once
+    dummyHash     []byte
+)
+
+// checkPassword: bcrypt 검증 (사용자가 없어도 같은 비용의 비교를 수행)
+func checkPassword(u *User, pw string) bool {
+    if u == nil {
+        dummyHashOnce.Do(func() {
+            dummyHash, _ = bcrypt.GenerateFromPassword([]byte(randomHex(16)), bcrypt.DefaultCost)
+        })
+        bcrypt.CompareHashAndPassword(dummyHash, []byte(pw))
+        return false
+    }
+    return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(pw)) == nil
+}
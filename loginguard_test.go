@@ -0,0 +1,235 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "os"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// resetLoginGuard: 로그인 제한 상태와 설정을 비우고 테스트가 끝나면 되돌린다
+func resetLoginGuard(t *testing.T) {
+    oldAccounts, oldIPs := accountAttempts, ipAttempts
+    oldMax, oldLockout, oldLimit := loginMaxFailures, loginLockout, loginAttemptLimit
+    accountAttempts = make(map[string]*loginAttempts)
+    ipAttempts = make(map[string]*loginAttempts)
+    t.Cleanup(func() {
+        accountAttempts, ipAttempts = oldAccounts, oldIPs
+        loginMaxFailures, loginLockout, loginAttemptLimit = oldMax, oldLockout, oldLimit
+    })
+}
+
+func TestLoginAttemptsWait(t *testing.T) {
+    now := time.Now()
+    tests := []struct {
+        name     string
+        attempts *loginAttempts
+        want     time.Duration
+    }{
+        {"기록 없음", nil, 0},
+        {"허용 횟수 이내", &loginAttempts{Failures: 3, LastFailure: now}, 0},
+        {"허용 횟수 + 1: 1초", &loginAttempts{Failures: 4, LastFailure: now}, time.Second},
+        {"허용 횟수 + 2: 2초", &loginAttempts{Failures: 5, LastFailure: now}, 2 * time.Second},
+        {"허용 횟수 + 4: 8초", &loginAttempts{Failures: 7, LastFailure: now}, 8 * time.Second},
+        {"이미 지난 시간만큼 줄어듦", &loginAttempts{Failures: 5, LastFailure: now.Add(-500 * time.Millisecond)}, 1500 * time.Millisecond},
+        {"대기 시간이 지나면 0 이하", &loginAttempts{Failures: 4, LastFailure: now.Add(-time.Minute)}, -59 * time.Second},
+        {"최대 5분", &loginAttempts{Failures: 3 + 30, LastFailure: now}, maxLoginBackoff},
+        {"매우 큰 실패 횟수", &loginAttempts{Failures: 1 << 20, LastFailure: now}, maxLoginBackoff},
+        {"잠금은 풀릴 때까지", &loginAttempts{Failures: 0, LockedUntil: now.Add(10 * time.Minute)}, 10 * time.Minute},
+    }
+    for _, tt := range tests {
+        if got := tt.attempts.wait(now, accountFreeFailures); got != tt.want {
+            t.Errorf("%s: wait = %v, want %v", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestLoginBackoffAndLockout(t *testing.T) {
+    resetLoginGuard(t)
+    setTestUsers(t, &User{Email: "user@test", Role: roleViewer})
+    loginMaxFailures, loginLockout = 5, time.Minute
+
+    // 허용 횟수까지는 대기 없음
+    for i := 1; i <= accountFreeFailures; i++ {
+        if loginFailed("user@test", fmt.Sprintf("192.0.2.%d", i)) {
+            t.Fatalf("%d번째 실패에서 잠기면 안 됨", i)
+        }
+        if wait, _ := loginBlocked("user@test", "192.0.2.100"); wait > 0 {
+            t.Fatalf("%d번째 실패 후 대기 %v", i, wait)
+        }
+    }
+    // 그다음부터는 IP 를 바꿔도 계정 기준으로 기다려야 한다
+    loginFailed("user@test", "192.0.2.50")
+    if wait, locked := loginBlocked("user@test", "192.0.2.100"); locked || wait <= 0 || wait > time.Second {
+        t.Fatalf("허용 횟수 초과 후: wait=%v locked=%v", wait, locked)
+    }
+    // login_max_failures 번째 실패에서 잠금
+    if !loginFailed("user@test", "192.0.2.51") {
+        t.Fatal("최대 실패 횟수에서 잠겨야 함")
+    }
+    wait, locked := loginBlocked("user@test", "192.0.2.100")
+    if !locked || wait <= loginLockout-time.Second || wait > loginLockout {
+        t.Fatalf("잠금: wait=%v locked=%v", wait, locked)
+    }
+    if got := lockedAccounts(); len(got) != 1 || got[0].Email != "user@test" || !got[0].Registered {
+        t.Fatalf("lockedAccounts = %+v", got)
+    }
+
+    // 성공하면 계정/IP 기록 삭제
+    loginSucceeded("user@test", "192.0.2.51")
+    if wait, locked := loginBlocked("user@test", "192.0.2.51"); wait > 0 || locked {
+        t.Fatalf("성공 후: wait=%v locked=%v", wait, locked)
+    }
+}
+
+func TestLoginIPBackoff(t *testing.T) {
+    resetLoginGuard(t)
+    setTestUsers(t)
+    ip := "198.51.100.7"
+    for i := 0; i < ipFreeFailures; i++ {
+        loginFailed(fmt.Sprintf("nobody%d@test", i), ip)
+    }
+    if wait, _ := loginBlocked("other@test", ip); wait > 0 {
+        t.Fatalf("IP 허용 횟수 이내인데 대기 %v", wait)
+    }
+    loginFailed("nobody@test", ip)
+    if wait, locked := loginBlocked("other@test", ip); wait <= 0 || locked {
+        t.Fatalf("IP 허용 횟수 초과 후: wait=%v locked=%v", wait, locked)
+    }
+    if wait, _ := loginBlocked("other@test", "198.51.100.8"); wait > 0 {
+        t.Fatalf("다른 IP 는 영향 없어야 함: %v", wait)
+    }
+}
+
+func TestLoginUnregisteredNotTracked(t *testing.T) {
+    resetLoginGuard(t)
+    setTestUsers(t, &User{Email: "user@test", Role: roleViewer})
+    loginMaxFailures = 3
+
+    for i := 0; i < 100; i++ {
+        if loginFailed(fmt.Sprintf("random%d@test", i), fmt.Sprintf("203.0.113.%d", i)) {
+            t.Fatal("미등록 이메일은 잠기지 않음")
+        }
+    }
+    if len(accountAttempts) != 0 {
+        t.Fatalf("미등록 이메일의 계정 기록이 남음: %d개", len(accountAttempts))
+    }
+    if len(ipAttempts) != 100 {
+        t.Fatalf("IP 기록 %d개, want 100", len(ipAttempts))
+    }
+}
+
+func TestLoginAttemptLimit(t *testing.T) {
+    resetLoginGuard(t)
+    setTestUsers(t, &User{Email: "locked@test", Role: roleViewer})
+    loginAttemptLimit = 50
+    loginMaxFailures = 1
+
+    // IP 기록이 최대 개수에 닿아도 계정 기록(잠금)은 따로 유지된다
+    loginFailed("locked@test", "198.51.100.1")
+    for i := 0; i < 500; i++ {
+        loginFailed("nobody@test", fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+    }
+    if len(ipAttempts) > loginAttemptLimit {
+        t.Fatalf("IP 기록 %d개, 최대 %d", len(ipAttempts), loginAttemptLimit)
+    }
+    if _, ok := ipAttempts["10.0.1.243"]; !ok {
+        t.Fatal("가장 최근 기록은 남아야 함")
+    }
+    if _, locked := loginBlocked("locked@test", "198.51.100.9"); !locked {
+        t.Fatal("잠긴 계정은 그대로 잠겨 있어야 함")
+    }
+
+    // 모두 잠긴 상태에서도 최대 개수를 넘지 않는다
+    m := make(map[string]*loginAttempts)
+    now := time.Now()
+    for i := 0; i < loginAttemptLimit*2; i++ {
+        a := attemptsFor(m, fmt.Sprint(i), now)
+        a.LockedUntil = now.Add(time.Duration(i+1) * time.Minute)
+    }
+    if len(m) != loginAttemptLimit {
+        t.Fatalf("기록 %d개, want %d", len(m), loginAttemptLimit)
+    }
+    if _, ok := m[fmt.Sprint(loginAttemptLimit*2-1)]; !ok {
+        t.Fatal("가장 늦게 풀리는 잠금은 남아야 함")
+    }
+}
+
+func TestPasswordResetClearsLockout(t *testing.T) {
+    resetLoginGuard(t)
+    u := &User{Email: "user@test", Role: roleViewer}
+    setTestUsers(t, u)
+    loginMaxFailures = 1
+    // saveAccounts/감사 로그가 임시 디렉토리에 쓰도록
+    dir, _ := os.Getwd()
+    if err := os.Chdir(t.TempDir()); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { os.Chdir(dir) })
+
+    if !loginFailed(u.Email, "192.0.2.1") {
+        t.Fatal("잠겨야 함")
+    }
+    r := testRouter()
+    r.POST("/password/reset", doResetPassword)
+    token := issueResetToken(u.Email)
+    w := testRequest(r, http.MethodPost, "/password/reset", url.Values{"token": {token}, "password": {"new-password"}}, nil)
+    if w.Code != http.StatusOK {
+        t.Fatalf("재설정 실패: %d %s", w.Code, w.Body.String())
+    }
+    if _, locked := loginBlocked(u.Email, "192.0.2.1"); locked {
+        t.Fatal("비밀번호 재설정 후에는 잠금이 풀려야 함")
+    }
+    if !checkPassword(u, "new-password") {
+        t.Fatal("새 비밀번호로 바뀌어야 함")
+    }
+}
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+    tests := []struct {
+        name    string
+        proxies string // .env 의 trusted_proxies
+        remote  string
+        xff     string
+        want    string
+    }{
+        {"설정 없음: X-Forwarded-For 무시", "", "192.0.2.10:1234", "1.2.3.4", "192.0.2.10"},
+        {"믿는 프록시", "10.0.0.1", "10.0.0.1:1234", "1.2.3.4", "1.2.3.4"},
+        {"믿는 대역", "10.0.0.0/8, 172.16.0.0/12", "172.16.5.5:1234", "1.2.3.4", "1.2.3.4"},
+        {"믿지 않는 주소의 X-Forwarded-For 는 무시", "10.0.0.0/8", "192.0.2.10:1234", "1.2.3.4", "192.0.2.10"},
+        {"클라이언트가 앞에 넣은 값은 무시", "10.0.0.0/8", "10.0.0.1:1234", "6.6.6.6, 1.2.3.4", "1.2.3.4"},
+        {"여러 프록시를 거친 경우", "10.0.0.0/8", "10.0.0.1:1234", "1.2.3.4, 10.0.0.2", "1.2.3.4"},
+        {"헤더 없음", "10.0.0.0/8", "10.0.0.1:1234", "", "10.0.0.1"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            t.Setenv("trusted_proxies", tt.proxies)
+            gin.SetMode(gin.TestMode)
+            r := gin.New()
+            if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+                t.Fatal(err)
+            }
+            r.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+            req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+            req.RemoteAddr = tt.remote
+            if tt.xff != "" {
+                req.Header.Set("X-Forwarded-For", tt.xff)
+            }
+            w := httptest.NewRecorder()
+            r.ServeHTTP(w, req)
+            if got := w.Body.String(); got != tt.want {
+                t.Fatalf("ClientIP = %s, want %s", got, tt.want)
+            }
+        })
+    }
+
+    t.Setenv("trusted_proxies", "not-an-ip")
+    if err := gin.New().SetTrustedProxies(trustedProxies()); err == nil {
+        t.Fatal("잘못된 trusted_proxies 는 오류여야 함")
+    }
+}
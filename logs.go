@@ -0,0 +1,99 @@
+package main
+
+import (
+    "bufio"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ------------------------------------------------------
+// 14. 컨테이너 로그 스트리밍 (SSE)
+// ------------------------------------------------------
+
+// 로그 스트리밍: "compose logs -f --tail N [service]" 출력을 SSE 로 전달
+// 클라이언트 연결이 끊기면 요청 컨텍스트가 취소되어 프로세스도 종료된다.
+func streamLogsAPI(c *gin.Context) {
+    p := c.Query("path")
+    if p == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "path 파라미터 필요"})
+        return
+    }
+    if composeCommand == "" {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "docker compose 명령이 감지되지 않았습니다."})
+        return
+    }
+    fullPath, err := resolvePath(p)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    tail := c.DefaultQuery("tail", "100")
+    if tail != "all" {
+        if n, err := strconv.Atoi(tail); err != nil || n < 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "tail 은 0 이상의 숫자 또는 all 이어야 합니다."})
+            return
+        }
+    }
+    args := []string{"logs", "-f", "--no-color", "--tail", tail}
+    if c.Query("timestamps") == "1" {
+        args = append(args, "--timestamps")
+    }
+    if service := c.Query("service"); service != "" {
+        // 옵션으로 해석될 수 있는 값 차단
+        if strings.HasPrefix(service, "-") {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 서비스명"})
+            return
+        }
+        args = append(args, service)
+    }
+
+    ctx := c.Request.Context()
+    cmd := composeExecContext(ctx, fullPath, args...)
+    pr, pw := io.Pipe()
+    defer pr.Close()
+    cmd.Stdout = pw
+    cmd.Stderr = pw
+    if err := cmd.Start(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    go func() {
+        pw.CloseWithError(cmd.Wait())
+    }()
+
+    // 출력을 줄 단위로 읽어 채널로 전달
+    lines := make(chan string)
+    go func() {
+        defer close(lines)
+        scanner := bufio.NewScanner(pr)
+        scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+        for scanner.Scan() {
+            select {
+            case lines <- scanner.Text():
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+
+    c.Header("Cache-Control", "no-cache")
+    c.Header("X-Accel-Buffering", "no")
+    c.Stream(func(w io.Writer) bool {
+        select {
+        case line, ok := <-lines:
+            if !ok {
+                c.SSEvent("end", "로그 스트림 종료")
+                return false
+            }
+            c.SSEvent("log", line)
+            return true
+        case <-ctx.Done():
+            return false
+        }
+    })
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+    "bytes"
+    "crypto/rand"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/base64"
+    "encoding/hex"
+    "fmt"
+    "log"
+    "mime"
+    "net"
+    netmail "net/mail"
+    "net/smtp"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "text/template"
+    "time"
+)
+
+// ------------------------------------------------------
+// 26. 메일 발송 (SMTP)
+// ------------------------------------------------------
+
+// SMTP 연결 보안 방식 (.env 의 smtp_tls)
+const (
+    smtpTLSNone     = "none"     // 평문 (로컬 테스트용 SMTP 등)
+    smtpTLSStartTLS = "starttls" // 평문 연결 후 STARTTLS (기본, 보통 587)
+    smtpTLSImplicit = "tls"      // 처음부터 TLS (보통 465)
+)
+
+// .env 의 smtp_* 설정
+type smtpConfig struct {
+    Host       string
+    Port       int
+    TLS        string
+    User       string
+    Password   string
+    From       string // 헤더용 (예: "Compose Console <console@example.com>")
+    FromAddr   string // MAIL FROM 용 주소만
+    Retries    int           // 실패 시 재시도 횟수
+    RetryDelay time.Duration // 첫 재시도 대기 시간 (이후 두 배씩)
+    RootCAs    *x509.CertPool // 서버 인증서 확인용 (nil 이면 시스템 인증서)
+}
+
+// 메일 본문 템플릿 위치: 첫 줄은 "Subject: 제목", 빈 줄 다음부터 본문
+const mailTemplateDir = "templates/mail"
+
+// 메일 템플릿 이름
+const (
+    mailApprovalNeeded = "approval_needed" // 신규 가입자 승인 요청 (어드민에게)
+    mailRoleChanged    = "role_changed"    // 권한 변경 안내 (해당 사용자에게)
+    mailPasswordReset  = "password_reset"  // 비밀번호 재설정 링크
+)
+
+// 메일 본문의 링크에 쓰는 외부 접속 주소 (.env 의 public_url, 없으면 http://localhost:포트)
+var publicURL string
+
+type mailMessage struct {
+    To      []string
+    Subject string
+    Body    string
+}
+
+type mailer struct {
+    cfg       smtpConfig
+    templates *template.Template
+    queue     chan mailMessage
+}
+
+// 전역 메일러 (runServer 에서 설정. smtp_host 가 없으면 로그만 남긴다)
+var mails *mailer
+
+// loadSMTPConfig: .env 의 smtp_host, smtp_port, smtp_tls, smtp_user, smtp_password, smtp_from,
+// smtp_retries 를 읽는다. smtp_host 가 없으면 ok=false
+func loadSMTPConfig() (cfg smtpConfig, ok bool, err error) {
+    cfg = smtpConfig{
+        Host:       os.Getenv("smtp_host"),
+        Port:       587,
+        TLS:        smtpTLSStartTLS,
+        User:       os.Getenv("smtp_user"),
+        Password:   os.Getenv("smtp_password"),
+        From:       os.Getenv("smtp_from"),
+        Retries:    3,
+        RetryDelay: 5 * time.Second,
+    }
+    if cfg.Host == "" {
+        return cfg, false, nil
+    }
+    if v := os.Getenv("smtp_port"); v != "" {
+        if cfg.Port, err = strconv.Atoi(v); err != nil {
+            return cfg, false, fmt.Errorf("smtp_port 값이 잘못되었습니다: %s", v)
+        }
+    }
+    if v := os.Getenv("smtp_tls"); v != "" {
+        if v != smtpTLSNone && v != smtpTLSStartTLS && v != smtpTLSImplicit {
+            return cfg, false, fmt.Errorf("smtp_tls 는 none, starttls, tls 중 하나여야 합니다: %s", v)
+        }
+        cfg.TLS = v
+    }
+    if v := os.Getenv("smtp_retries"); v != "" {
+        if cfg.Retries, err = strconv.Atoi(v); err != nil || cfg.Retries < 0 {
+            return cfg, false, fmt.Errorf("smtp_retries 값이 잘못되었습니다: %s", v)
+        }
+    }
+    if cfg.From == "" {
+        cfg.From = cfg.User
+    }
+    from, err := netmail.ParseAddress(cfg.From)
+    if err != nil {
+        return cfg, false, fmt.Errorf("smtp_from 주소가 잘못되었습니다: %q", cfg.From)
+    }
+    cfg.From, cfg.FromAddr = from.String(), from.Address
+    return cfg, true, nil
+}
+
+// newMailer: 템플릿을 읽고 발송 작업자를 시작한다. enabled 가 false 면 발송 대신 로그만 남긴다.
+func newMailer(cfg smtpConfig, enabled bool) (*mailer, error) {
+    tmpl, err := template.ParseGlob(filepath.Join(mailTemplateDir, "*.txt"))
+    if err != nil {
+        return nil, fmt.Errorf("메일 템플릿 로드 실패: %v", err)
+    }
+    m := &mailer{cfg: cfg, templates: tmpl}
+    if enabled {
+        m.queue = make(chan mailMessage, 100)
+        go m.worker()
+    }
+    return m, nil
+}
+
+// send: 템플릿 name 으로 메일을 만들어 발송 대기열에 넣는다 (발송은 비동기)
+func (m *mailer) send(to []string, name string, data interface{}) error {
+    if m == nil {
+        return fmt.Errorf("메일러가 초기화되지 않았습니다.")
+    }
+    for _, addr := range to {
+        if _, err := netmail.ParseAddress(addr); err != nil || strings.ContainsAny(addr, "\r\n") {
+            return fmt.Errorf("잘못된 수신 주소: %q", addr)
+        }
+    }
+    if len(to) == 0 {
+        return nil
+    }
+    var buf bytes.Buffer
+    if err := m.templates.ExecuteTemplate(&buf, name+".txt", data); err != nil {
+        return fmt.Errorf("메일 템플릿(%s) 오류: %v", name, err)
+    }
+    msg := mailMessage{To: to}
+    header, body, _ := strings.Cut(buf.String(), "\n\n")
+    msg.Subject = strings.TrimSpace(strings.TrimPrefix(header, "Subject:"))
+    msg.Body = body
+
+    if m.queue == nil {
+        log.Printf("[이메일 발송] (SMTP 미설정) %s: %s", strings.Join(to, ", "), msg.Subject)
+        return nil
+    }
+    select {
+    case m.queue <- msg:
+        return nil
+    default:
+        return fmt.Errorf("메일 대기열이 가득 찼습니다.")
+    }
+}
+
+// worker: 대기열의 메일을 하나씩 발송하며 실패하면 간격을 두 배씩 늘려 재시도
+func (m *mailer) worker() {
+    for msg := range m.queue {
+        delay := m.cfg.RetryDelay
+        for attempt := 0; ; attempt++ {
+            err := m.deliver(msg)
+            if err == nil {
+                log.Printf("[이메일 발송] %s: %s", strings.Join(msg.To, ", "), msg.Subject)
+                break
+            }
+            if attempt >= m.cfg.Retries {
+                log.Printf("[이메일 발송 실패] %s: %s (%d회 시도): %v", strings.Join(msg.To, ", "), msg.Subject, attempt+1, err)
+                break
+            }
+            log.Printf("[이메일 발송 재시도] %s: %v (%v 후)", strings.Join(msg.To, ", "), err, delay)
+            time.Sleep(delay)
+            delay *= 2
+        }
+    }
+}
+
+// deliver: SMTP 서버로 한 통 발송
+func (m *mailer) deliver(msg mailMessage) error {
+    addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+    tlsConfig := &tls.Config{ServerName: m.cfg.Host, RootCAs: m.cfg.RootCAs}
+
+    var conn net.Conn
+    var err error
+    dialer := &net.Dialer{Timeout: 30 * time.Second}
+    if m.cfg.TLS == smtpTLSImplicit {
+        conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+    } else {
+        conn, err = dialer.Dial("tcp", addr)
+    }
+    if err != nil {
+        return err
+    }
+    conn.SetDeadline(time.Now().Add(2 * time.Minute))
+    c, err := smtp.NewClient(conn, m.cfg.Host)
+    if err != nil {
+        conn.Close()
+        return err
+    }
+    defer c.Close()
+
+    if m.cfg.TLS == smtpTLSStartTLS {
+        if err := c.StartTLS(tlsConfig); err != nil {
+            return fmt.Errorf("STARTTLS 실패: %v", err)
+        }
+    }
+    if m.cfg.User != "" {
+        if err := c.Auth(smtp.PlainAuth("", m.cfg.User, m.cfg.Password, m.cfg.Host)); err != nil {
+            return fmt.Errorf("SMTP 인증 실패: %v", err)
+        }
+    }
+    if err := c.Mail(m.cfg.FromAddr); err != nil {
+        return err
+    }
+    for _, to := range msg.To {
+        if err := c.Rcpt(to); err != nil {
+            return err
+        }
+    }
+    w, err := c.Data()
+    if err != nil {
+        return err
+    }
+    if _, err := w.Write(buildMessage(m.cfg.From, msg)); err != nil {
+        return err
+    }
+    if err := w.Close(); err != nil {
+        return err
+    }
+    return c.Quit()
+}
+
+// buildMessage: 헤더 + base64 본문 (한글 제목은 RFC 2047 인코딩)
+func buildMessage(from string, msg mailMessage) []byte {
+    var b bytes.Buffer
+    fmt.Fprintf(&b, "From: %s\r\n", from)
+    fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+    fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Subject))
+    fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+    fmt.Fprintf(&b, "Message-ID: <%s@dc_webconsole>\r\n", randomHex(12))
+    b.WriteString("MIME-Version: 1.0\r\n")
+    b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+    b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+    body := base64.StdEncoding.EncodeToString([]byte(strings.ReplaceAll(msg.Body, "\n", "\r\n")))
+    for len(body) > 76 {
+        b.WriteString(body[:76] + "\r\n")
+        body = body[76:]
+    }
+    b.WriteString(body + "\r\n")
+    return b.Bytes()
+}
+
+func randomHex(n int) string {
+    buf := make([]byte, n)
+    rand.Read(buf)
+    return hex.EncodeToString(buf)
+}
+
+// adminEmails: 전역 admin 역할 사용자 이메일
+func adminEmails() []string {
+    var result []string
+    for _, u := range users {
+        if u.Role == roleAdmin {
+            result = append(result, u.Email)
+        }
+    }
+    return result
+}
+
+// notify: 메일 발송 요청 (실패해도 요청 처리는 계속하고 로그만 남긴다)
+func notify(to []string, name string, data map[string]interface{}) {
+    data["URL"] = publicURL
+    if err := mails.send(to, name, data); err != nil {
+        log.Printf("[경고] 메일(%s) 발송 요청 실패: %v", name, err)
+    }
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+    "bufio"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/tls"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/base64"
+    "io/ioutil"
+    "math/big"
+    "mime"
+    "net"
+    netmail "net/mail"
+    "net/textproto"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// 테스트용 SMTP 서버가 받은 메일
+type fakeMail struct {
+    From string
+    To   []string
+    Data string
+    TLS  bool   // STARTTLS 후에 받았는지
+    Auth string // AUTH PLAIN 으로 받은 "\x00user\x00password"
+}
+
+// fakeSMTP: net.Listen 기반의 최소 SMTP 서버
+// rejectFirst 개의 연결은 421 로 거절해 일시 장애를 흉내 낸다.
+type fakeSMTP struct {
+    ln          net.Listener
+    tlsConfig   *tls.Config // nil 이면 STARTTLS 를 광고하지 않음
+    rejectFirst int
+
+    mu    sync.Mutex
+    conns int
+    mails []fakeMail
+    got   chan fakeMail
+}
+
+func startFakeSMTP(t *testing.T, tlsConfig *tls.Config, rejectFirst int) *fakeSMTP {
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    s := &fakeSMTP{ln: ln, tlsConfig: tlsConfig, rejectFirst: rejectFirst, got: make(chan fakeMail, 10)}
+    t.Cleanup(func() { ln.Close() })
+    go func() {
+        for {
+            conn, err := ln.Accept()
+            if err != nil {
+                return
+            }
+            go s.serve(conn)
+        }
+    }()
+    return s
+}
+
+func (s *fakeSMTP) port() int {
+    return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) connCount() int {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.conns
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+    defer conn.Close()
+    s.mu.Lock()
+    s.conns++
+    reject := s.conns <= s.rejectFirst
+    s.mu.Unlock()
+
+    tp := textproto.NewConn(conn)
+    if reject {
+        tp.PrintfLine("421 4.3.2 잠시 후 다시 시도")
+        return
+    }
+    tp.PrintfLine("220 fake ESMTP")
+    var mail fakeMail
+    for {
+        line, err := tp.ReadLine()
+        if err != nil {
+            return
+        }
+        cmd, arg, _ := strings.Cut(line, " ")
+        switch strings.ToUpper(cmd) {
+        case "EHLO", "HELO":
+            if s.tlsConfig != nil && !mail.TLS {
+                tp.PrintfLine("250-fake")
+                tp.PrintfLine("250-STARTTLS")
+            } else {
+                tp.PrintfLine("250-fake")
+            }
+            tp.PrintfLine("250 AUTH PLAIN")
+        case "STARTTLS":
+            if s.tlsConfig == nil {
+                tp.PrintfLine("502 지원하지 않음")
+                continue
+            }
+            tp.PrintfLine("220 시작")
+            tlsConn := tls.Server(conn, s.tlsConfig)
+            if err := tlsConn.Handshake(); err != nil {
+                return
+            }
+            conn = tlsConn
+            tp = textproto.NewConn(tlsConn)
+            mail.TLS = true
+        case "AUTH":
+            _, cred, _ := strings.Cut(arg, " ")
+            raw, _ := base64.StdEncoding.DecodeString(cred)
+            mail.Auth = string(raw)
+            tp.PrintfLine("235 인증됨")
+        case "MAIL":
+            mail.From = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+            tp.PrintfLine("250 ok")
+        case "RCPT":
+            mail.To = append(mail.To, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+            tp.PrintfLine("250 ok")
+        case "DATA":
+            tp.PrintfLine("354 보내세요")
+            data, err := tp.ReadDotBytes()
+            if err != nil {
+                return
+            }
+            mail.Data = string(data)
+            s.mu.Lock()
+            s.mails = append(s.mails, mail)
+            s.mu.Unlock()
+            s.got <- mail
+            tp.PrintfLine("250 접수")
+        case "QUIT":
+            tp.PrintfLine("221 bye")
+            return
+        default:
+            tp.PrintfLine("502 알 수 없는 명령")
+        }
+    }
+}
+
+// selfSignedTLS: 127.0.0.1 용 자체 서명 인증서 (서버 설정, 클라이언트가 믿을 인증서 풀)
+func selfSignedTLS(t *testing.T) (*tls.Config, *x509.CertPool) {
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+    tmpl := &x509.Certificate{
+        SerialNumber: big.NewInt(1),
+        Subject:      pkix.Name{CommonName: "fake smtp"},
+        NotBefore:    time.Now().Add(-time.Hour),
+        NotAfter:     time.Now().Add(time.Hour),
+        IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+        KeyUsage:     x509.KeyUsageDigitalSignature,
+        ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+    }
+    der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+    if err != nil {
+        t.Fatal(err)
+    }
+    cert, err := x509.ParseCertificate(der)
+    if err != nil {
+        t.Fatal(err)
+    }
+    pool := x509.NewCertPool()
+    pool.AddCert(cert)
+    return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, pool
+}
+
+func testSMTPConfig(s *fakeSMTP, mode string) smtpConfig {
+    return smtpConfig{
+        Host:       "127.0.0.1",
+        Port:       s.port(),
+        TLS:        mode,
+        From:       `"Compose Console" <console@example.com>`,
+        FromAddr:   "console@example.com",
+        RetryDelay: time.Millisecond,
+    }
+}
+
+var testMail = mailMessage{
+    To:      []string{"a@example.com", "b@example.com"},
+    Subject: "[도커 컴포즈 웹콘솔] 권한이 변경되었습니다",
+    Body:    "첫 줄\n둘째 줄\n",
+}
+
+func TestDeliverNone(t *testing.T) {
+    s := startFakeSMTP(t, nil, 0)
+    m := &mailer{cfg: testSMTPConfig(s, smtpTLSNone)}
+    if err := m.deliver(testMail); err != nil {
+        t.Fatal(err)
+    }
+    got := <-s.got
+    if got.TLS || got.Auth != "" {
+        t.Errorf("평문 연결이어야 하고 인증은 없어야 함: %+v", got)
+    }
+    if got.From != "console@example.com" {
+        t.Errorf("MAIL FROM = %q", got.From)
+    }
+    if strings.Join(got.To, ",") != "a@example.com,b@example.com" {
+        t.Errorf("RCPT TO = %v", got.To)
+    }
+    msg, err := netmail.ReadMessage(strings.NewReader(got.Data))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != testMail.Subject {
+        t.Errorf("Subject = %q", subject)
+    }
+}
+
+func TestDeliverStartTLS(t *testing.T) {
+    serverTLS, pool := selfSignedTLS(t)
+    s := startFakeSMTP(t, serverTLS, 0)
+    cfg := testSMTPConfig(s, smtpTLSStartTLS)
+    cfg.User, cfg.Password = "user", "secret"
+    cfg.RootCAs = pool
+    m := &mailer{cfg: cfg}
+    if err := m.deliver(testMail); err != nil {
+        t.Fatal(err)
+    }
+    got := <-s.got
+    if !got.TLS {
+        t.Error("STARTTLS 후에 발송해야 함")
+    }
+    if got.Auth != "\x00user\x00secret" {
+        t.Errorf("AUTH PLAIN = %q", got.Auth)
+    }
+
+    // 서버 인증서를 믿을 수 없으면 평문으로 내려가지 않고 실패
+    cfg.RootCAs = x509.NewCertPool()
+    m = &mailer{cfg: cfg}
+    if err := m.deliver(testMail); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+        t.Errorf("믿을 수 없는 인증서: err = %v", err)
+    }
+}
+
+func TestDeliverStartTLSUnsupported(t *testing.T) {
+    s := startFakeSMTP(t, nil, 0)
+    m := &mailer{cfg: testSMTPConfig(s, smtpTLSStartTLS)}
+    if err := m.deliver(testMail); err == nil {
+        t.Fatal("STARTTLS 를 지원하지 않는 서버로는 발송하지 않아야 함")
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if len(s.mails) != 0 {
+        t.Fatalf("평문으로 발송됨: %+v", s.mails)
+    }
+}
+
+func TestBuildMessage(t *testing.T) {
+    body := strings.Repeat("긴 본문 ", 40) + "\n끝"
+    raw := buildMessage(`"Compose Console" <console@example.com>`, mailMessage{
+        To: []string{"a@example.com", "b@example.com"}, Subject: testMail.Subject, Body: body,
+    })
+
+    // 모든 줄은 CRLF 로 끝나고, base64 본문은 76자씩 나뉜다
+    _, encodedBody, ok := strings.Cut(string(raw), "\r\n\r\n")
+    if !ok {
+        t.Fatal("헤더와 본문 사이에 빈 줄이 없음")
+    }
+    for _, line := range strings.SplitAfter(string(raw), "\r\n") {
+        if line != "" && (!strings.HasSuffix(line, "\r\n") || strings.Contains(strings.TrimSuffix(line, "\r\n"), "\n")) {
+            t.Fatalf("CRLF 가 아닌 줄: %q", line)
+        }
+    }
+    for _, line := range strings.Split(strings.TrimSuffix(encodedBody, "\r\n"), "\r\n") {
+        if len(line) > 76 {
+            t.Fatalf("본문 줄이 76자를 넘음 (%d): %q", len(line), line)
+        }
+    }
+
+    msg, err := netmail.ReadMessage(strings.NewReader(string(raw)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    h := msg.Header
+    want := map[string]string{
+        "From":                      `"Compose Console" <console@example.com>`,
+        "To":                        "a@example.com, b@example.com",
+        "Mime-Version":              "1.0",
+        "Content-Type":              "text/plain; charset=UTF-8",
+        "Content-Transfer-Encoding": "base64",
+    }
+    for k, v := range want {
+        if got := h.Get(k); got != v {
+            t.Errorf("%s = %q, want %q", k, got, v)
+        }
+    }
+    if strings.Contains(h.Get("Subject"), "도커") {
+        t.Errorf("한글 제목은 RFC 2047 로 인코딩해야 함: %q", h.Get("Subject"))
+    }
+    if subject, err := new(mime.WordDecoder).DecodeHeader(h.Get("Subject")); err != nil || subject != testMail.Subject {
+        t.Errorf("Subject = %q (%v)", subject, err)
+    }
+    if _, err := h.Date(); err != nil {
+        t.Errorf("Date 헤더: %v", err)
+    }
+    if id := h.Get("Message-Id"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@dc_webconsole>") {
+        t.Errorf("Message-ID = %q", id)
+    }
+
+    encoded, _ := ioutil.ReadAll(msg.Body)
+    decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := strings.ReplaceAll(body, "\n", "\r\n"); string(decoded) != want {
+        t.Errorf("본문 = %q, want %q", decoded, want)
+    }
+}
+
+func TestMailerRetry(t *testing.T) {
+    // 처음 두 번은 421 로 거절, 세 번째에 성공
+    s := startFakeSMTP(t, nil, 2)
+    cfg := testSMTPConfig(s, smtpTLSNone)
+    cfg.Retries = 3
+    m := &mailer{cfg: cfg, queue: make(chan mailMessage, 1)}
+    go m.worker()
+    defer close(m.queue)
+
+    m.queue <- testMail
+    select {
+    case got := <-s.got:
+        if len(got.To) != 2 {
+            t.Errorf("RCPT TO = %v", got.To)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("재시도 후에도 발송되지 않음")
+    }
+    if n := s.connCount(); n != 3 {
+        t.Errorf("연결 %d번, want 3", n)
+    }
+}
+
+func TestMailerRetryGivesUp(t *testing.T) {
+    s := startFakeSMTP(t, nil, 100)
+    cfg := testSMTPConfig(s, smtpTLSNone)
+    cfg.Retries = 2
+    m := &mailer{cfg: cfg, queue: make(chan mailMessage, 1)}
+    done := make(chan struct{})
+    go func() {
+        m.worker()
+        close(done)
+    }()
+
+    m.queue <- testMail
+    close(m.queue)
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatal("재시도를 멈추지 않음")
+    }
+    if n := s.connCount(); n != cfg.Retries+1 {
+        t.Errorf("연결 %d번, want %d", n, cfg.Retries+1)
+    }
+}
+
+func TestMailerSend(t *testing.T) {
+    s := startFakeSMTP(t, nil, 0)
+    m, err := newMailer(testSMTPConfig(s, smtpTLSNone), true)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer close(m.queue)
+
+    if err := m.send([]string{"bad\r\nBcc: x@example.com"}, mailRoleChanged, nil); err == nil {
+        t.Error("헤더를 주입하는 주소는 거부해야 함")
+    }
+    if err := m.send([]string{"u@example.com"}, "no_such_template", nil); err == nil {
+        t.Error("없는 템플릿은 오류여야 함")
+    }
+    err = m.send([]string{"u@example.com"}, mailRoleChanged, map[string]interface{}{
+        "Email": "u@example.com", "OldRole": "viewer", "NewRole": "operator", "ChangedBy": "admin@example.com", "URL": "http://console",
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    select {
+    case got := <-s.got:
+        msg, err := netmail.ReadMessage(bufio.NewReader(strings.NewReader(got.Data)))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "[도커 컴포즈 웹콘솔] 권한이 변경되었습니다" {
+            t.Errorf("Subject = %q", subject)
+        }
+        encoded, _ := ioutil.ReadAll(msg.Body)
+        body, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+        for _, want := range []string{"viewer", "operator", "admin@example.com", "http://console/console"} {
+            if !strings.Contains(string(body), want) {
+                t.Errorf("본문에 %q 가 없음: %s", want, body)
+            }
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("발송되지 않음")
+    }
+}
@@ -2,6 +2,7 @@ package main
 
 import (
     "bufio"
+    "bytes"
     "errors"
     "fmt"
     "io"
@@ -17,7 +18,6 @@ import (
     "time"
 
     "github.com/gin-contrib/sessions"
-    "github.com/gin-contrib/sessions/cookie"
     "github.com/gin-gonic/gin"
     "github.com/joho/godotenv"
     "golang.org/x/crypto/bcrypt"
@@ -30,13 +30,24 @@ import (
 type User struct {
     Email    string
     Password string // bcrypt 해시
-    Role     string // "admin" 또는 "none"
+    Role     string // "none", "viewer", "operator", "admin" (rbac.go)
+    Grants   map[string]string // 프로젝트별 역할 (예: "payments" -> "operator")
+
+    // 2단계 인증 (totp.go)
+    TOTPSecret    string   // base32 비밀키, 비어 있으면 미등록
+    RecoveryCodes []string // 남은 복구 코드의 SHA-256
+    Require2FA    bool     // 어드민이 등록을 요구함
 }
 
 var users = make(map[string]*User)
 
 const accountFile = ".account"
 
+// .account 파일 형식 버전 (첫 줄 헤더). 헤더가 없으면 예전(admin/none 만 있던) 형식이다.
+// v2 이후 필드는 뒤에만 추가하며, 없는 필드는 빈 값으로 읽는다.
+const accountHeaderPrefix = "#dc_webconsole accounts v2"
+const accountHeader = accountHeaderPrefix + ": email,bcrypt,role,grants,totp,recovery,require_2fa"
+
 // docker-compose 파일이 저장될 디렉토리
 var baseDir = "./docker-compose-list"
 
@@ -62,8 +73,15 @@ func loadAccounts() error {
 
     scanner := bufio.NewScanner(f)
     lineCount := 0
+    legacy := true
     for scanner.Scan() {
         line := scanner.Text()
+        if strings.HasPrefix(line, "#") {
+            if strings.HasPrefix(line, accountHeaderPrefix) {
+                legacy = false
+            }
+            continue
+        }
         fields := strings.Split(line, ",")
         if len(fields) < 3 {
             continue
@@ -71,6 +89,10 @@ func loadAccounts() error {
         email := fields[0]
         password := fields[1]
         role := fields[2]
+        if !validRole(role) {
+            log.Printf("[경고] %s 의 알 수 없는 권한(%s)을 %s 로 변경합니다.", email, role, roleNone)
+            role = roleNone
+        }
 
         // 첫 번째 라인이라면 firstRegisteredUserEmail 설정
         lineCount++
@@ -78,21 +100,79 @@ func loadAccounts() error {
             firstRegisteredUserEmail = email
         }
 
-        users[email] = &User{Email: email, Password: password, Role: role}
+        u := &User{Email: email, Password: password, Role: role}
+        if len(fields) > 3 {
+            grants, err := parseGrants(fields[3])
+            if err != nil {
+                log.Printf("[경고] %s 의 프로젝트 권한을 읽지 못했습니다: %v", email, err)
+            }
+            u.Grants = grants
+        }
+        if len(fields) > 4 {
+            u.TOTPSecret = fields[4]
+        }
+        if len(fields) > 5 && fields[5] != "" {
+            u.RecoveryCodes = strings.Split(fields[5], ";")
+        }
+        if len(fields) > 6 {
+            u.Require2FA = fields[6] == "1"
+        }
+        users[email] = u
+    }
+    if err := scanner.Err(); err != nil {
+        return err
+    }
+    if legacy && lineCount > 0 {
+        return migrateAccounts()
+    }
+    return nil
+}
+
+// migrateAccounts: 예전 형식의 .account 를 .account.v1.bak 으로 남기고 새 형식으로 다시 저장
+// (예전 admin/none 역할은 그대로 유효하다)
+func migrateAccounts() error {
+    data, err := ioutil.ReadFile(accountFile)
+    if err != nil {
+        return err
     }
-    return scanner.Err()
+    if err := ioutil.WriteFile(accountFile+".v1.bak", data, 0600); err != nil {
+        return err
+    }
+    log.Printf("%s 파일을 새 형식으로 변환합니다. (원본: %s.v1.bak)", accountFile, accountFile)
+    return saveAccounts()
 }
 
 
 func saveAccounts() error {
-    f, err := os.Create(accountFile)
+    // 비밀번호 해시와 OTP 비밀키가 있으므로 소유자만 읽을 수 있게
+    f, err := os.OpenFile(accountFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
     if err != nil {
         return err
     }
     defer f.Close()
 
-    for _, u := range users {
-        line := fmt.Sprintf("%s,%s,%s\n", u.Email, u.Password, u.Role)
+    if _, err := fmt.Fprintln(f, accountHeader); err != nil {
+        return err
+    }
+    // 첫 번째 가입자(대표 어드민)를 맨 앞에, 나머지는 이메일 순으로 기록
+    emails := make([]string, 0, len(users))
+    for email := range users {
+        if email != firstRegisteredUserEmail {
+            emails = append(emails, email)
+        }
+    }
+    sort.Strings(emails)
+    if _, ok := users[firstRegisteredUserEmail]; ok {
+        emails = append([]string{firstRegisteredUserEmail}, emails...)
+    }
+    for _, email := range emails {
+        u := users[email]
+        require2FA := ""
+        if u.Require2FA {
+            require2FA = "1"
+        }
+        line := fmt.Sprintf("%s,%s,%s,%s,%s,%s,%s\n", u.Email, u.Password, u.Role, formatGrants(u.Grants),
+            u.TOTPSecret, strings.Join(u.RecoveryCodes, ";"), require2FA)
         if _, err := f.WriteString(line); err != nil {
             return err
         }
@@ -113,34 +193,59 @@ func createUser(email, hashedPwd, role string) error {
 // ======================================================
 
 func landingPage(c *gin.Context) {
-    c.HTML(http.StatusOK, "landing.html", nil)
+    c.HTML(http.StatusOK, "landing.html", gin.H{"CSRFToken": csrfToken(c)})
 }
 
 func doLogin(c *gin.Context) {
     email := c.PostForm("email")
     pw := c.PostForm("password")
 
-    user, ok := users[email]
-    if !ok {
-        c.String(http.StatusUnauthorized, "등록되지 않은 이메일입니다.")
+    ip := c.ClientIP()
+
+    ev := auditEntry{User: email, IP: ip, Action: "login"}
+    // 실패가 반복된 계정/IP 는 잠시 기다리게 하고, 잠긴 계정은 비밀번호를 확인하지 않음
+    if wait, locked := loginBlocked(email, ip); wait > 0 {
+        secs := int(wait.Seconds() + 0.999)
+        c.Header("Retry-After", strconv.Itoa(secs))
+        if locked {
+            ev.record(errors.New("계정 잠김"))
+            c.String(http.StatusTooManyRequests, fmt.Sprintf("로그인 실패가 반복되어 계정이 잠겼습니다. %d분 후 다시 시도하거나 어드민에게 문의하세요.", (secs+59)/60))
+        } else {
+            ev.record(errors.New("시도 간격 제한"))
+            c.String(http.StatusTooManyRequests, fmt.Sprintf("로그인 시도가 너무 많습니다. %d초 후 다시 시도하세요.", secs))
+        }
+        return
+    }
+    // 비밀번호 검증 (등록되지 않은 이메일과 비밀번호 불일치는 같은 응답)
+    user := users[email]
+    if !checkPassword(user, pw) {
+        reason := "비밀번호 불일치"
+        if user == nil {
+            reason = "등록되지 않은 이메일"
+        }
+        if loginFailed(email, ip) {
+            reason += " (계정 잠김)"
+        }
+        ev.record(errors.New(reason))
+        c.String(http.StatusUnauthorized, loginFailedMessage)
         return
     }
-    // 비밀번호 검증
-    if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(pw)); err != nil {
-        c.String(http.StatusUnauthorized, "비밀번호가 일치하지 않습니다.")
+    // 2단계 인증을 등록한 사용자는 코드 확인 후 로그인 (실패 기록도 그때 초기화)
+    if user.TOTPSecret != "" {
+        beginSecondFactor(c, email)
         return
     }
+    loginSucceeded(email, ip)
 
-    sess := sessions.Default(c)
-    sess.Set("user_email", email)
-    sess.Save()
+    startSession(c, email)
+    ev.record(nil)
 
     // 로그인 후 콘솔 페이지로 이동
     c.Redirect(http.StatusFound, "/console")
 }
 
 func showRegister(c *gin.Context) {
-    c.HTML(http.StatusOK, "register.html", nil)
+    c.HTML(http.StatusOK, "register.html", gin.H{"CSRFToken": csrfToken(c)})
 }
 
 func doRegister(c *gin.Context) {
@@ -157,25 +262,27 @@ func doRegister(c *gin.Context) {
     }
 
     // 첫 사용자 -> admin
-    role := "none"
+    role := roleNone
     if len(users) == 0 {
-        role = "admin"
+        role = roleAdmin
+        firstRegisteredUserEmail = email
     }
 
+    ev := auditEntry{User: email, IP: c.ClientIP(), Action: "register", Detail: role}
     if err := createUser(email, string(hashed), role); err != nil {
+        ev.record(err)
         c.String(http.StatusConflict, fmt.Sprintf("회원가입 오류: %v", err))
         return
     }
+    ev.record(nil)
 
-    // 첫 회원이 아니면 어드민에게 알림 (예시 로그)
-    if role != "admin" {
-        log.Printf("[이메일 발송] 신규 회원(%s) 가입! 어드민 권한 부여 필요.\n", email)
+    // 첫 회원이 아니면 어드민에게 승인 요청 메일
+    if role != roleAdmin {
+        notify(adminEmails(), mailApprovalNeeded, map[string]interface{}{"Email": email, "Time": time.Now()})
     }
 
     // 회원가입 후 자동 로그인
-    sess := sessions.Default(c)
-    sess.Set("user_email", email)
-    sess.Save()
+    startSession(c, email)
 
     c.Redirect(http.StatusFound, "/console")
 }
@@ -186,8 +293,20 @@ func doRegister(c *gin.Context) {
 
 func AuthRequired() gin.HandlerFunc {
     return func(c *gin.Context) {
-        sess := sessions.Default(c)
-        if sess.Get("user_email") == nil {
+        // 스크립트/CI 는 세션 대신 API 토큰 사용 (tokens.go)
+        if raw := bearerToken(c); raw != "" {
+            t, err := authenticateToken(raw)
+            if err != nil {
+                c.String(http.StatusUnauthorized, "API 토큰 인증 실패: "+err.Error())
+                c.Abort()
+                return
+            }
+            c.Set(ctxAPIToken, t)
+            c.Next()
+            return
+        }
+        // 로그인하지 않았거나 세션이 만료(유휴/최대 시간)되었으면 랜딩으로
+        if !checkSession(c) {
             c.Redirect(http.StatusFound, "/")
             c.Abort()
             return
@@ -204,8 +323,11 @@ func doLogout(c *gin.Context) {
 }
 
 func currentUser(c *gin.Context) *User {
+    if t := requestToken(c); t != nil {
+        return users[t.Owner]
+    }
     sess := sessions.Default(c)
-    email := sess.Get("user_email")
+    email := sess.Get(sessionUserKey)
     if email == nil {
         return nil
     }
@@ -217,7 +339,7 @@ func currentUser(c *gin.Context) *User {
 }
 
 func isAdmin(u *User) bool {
-    return u != nil && u.Role == "admin"
+    return hasPermission(u, permAdmin)
 }
 
 
@@ -253,9 +375,12 @@ func consolePage(c *gin.Context) {
         return
     }
     data := gin.H{
-        "Email":   user.Email,
-        "Role":    user.Role,
-        "IsAdmin": isAdmin(user),
+        "Email":      user.Email,
+        "Role":       user.Role,
+        "IsAdmin":    isAdmin(user),
+        "CanEdit":    hasAnyPermission(user, permEdit),
+        "CanOperate": hasAnyPermission(user, permOperate),
+        "CSRFToken":  csrfToken(c),
     }
     c.HTML(http.StatusOK, "console.html", data)
 }
@@ -267,13 +392,25 @@ func listDirectoriesAPI(c *gin.Context) {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
+    c.JSON(http.StatusOK, visibleDirectories(currentUser(c), dirs))
+}
+
+// visibleDirectories: 조회 권한이 있는 디렉토리와 등록 프로젝트("@이름")
+func visibleDirectories(u *User, dirs []os.FileInfo) []string {
     var result []string
     for _, d := range dirs {
-        if d.IsDir() {
+        // .git 등 숨김 디렉토리는 제외
+        if d.IsDir() && !strings.HasPrefix(d.Name(), ".") && hasProjectPermission(u, d.Name(), permRead) {
             result = append(result, d.Name())
         }
     }
-    c.JSON(http.StatusOK, result)
+    // 등록된 프로젝트는 "@이름" 으로 표시
+    for _, p := range listProjects() {
+        if hasProjectPermission(u, projectPrefix+p.Name, permRead) {
+            result = append(result, projectPrefix+p.Name)
+        }
+    }
+    return result
 }
 
 // 파일 목록 (AJAX)
@@ -283,7 +420,11 @@ func listFilesAPI(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "dir 파라미터 필요"})
         return
     }
-    fullDir := filepath.Join(baseDir, dir)
+    fullDir, err := resolvePath(dir)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     infos, err := ioutil.ReadDir(fullDir)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -305,15 +446,26 @@ func getFileContentAPI(c *gin.Context) {
         c.String(http.StatusBadRequest, "path 필요")
         return
     }
-    fullPath := filepath.Join(baseDir, p)
+    fullPath, err := resolvePath(p)
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
     data, err := ioutil.ReadFile(fullPath)
     if err != nil {
         c.String(http.StatusInternalServerError, fmt.Sprintf("파일 읽기 오류: %v", err))
         return
     }
+    // 저장 시 충돌 감지를 위한 내용 해시
+    c.Header("ETag", fileETag(data))
     c.Data(http.StatusOK, "text/plain; charset=utf-8", data)
 }
 
+// fileETag: 파일 내용 기준 ETag (따옴표 포함)
+func fileETag(data []byte) string {
+    return `"` + contentHash(data) + `"`
+}
+
 // 파일 저장 (백업 후 저장, 필요 시 도커 재시작)
 func saveFileAPI(c *gin.Context) {
     p := c.PostForm("path")
@@ -323,30 +475,120 @@ func saveFileAPI(c *gin.Context) {
         c.String(http.StatusBadRequest, "path 필요")
         return
     }
-    fullPath := filepath.Join(baseDir, p)
+    fullPath, err := resolvePath(p)
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
 
-    // 저장 전 백업
-    if err := backupFile(fullPath); err != nil {
-        c.String(http.StatusInternalServerError, fmt.Sprintf("백업 실패: %v", err))
+    // 충돌 검사: 불러올 때 받은 ETag 와 현재 디스크 내용 비교
+    etag := c.PostForm("etag")
+    if etag == "" {
+        etag = c.GetHeader("If-Match")
+    }
+    if etag == "" {
+        c.String(http.StatusPreconditionRequired, "etag 필요 (파일을 다시 불러온 뒤 저장하세요)")
         return
     }
-    // 새 내용 저장
-    if err := ioutil.WriteFile(fullPath, []byte(content), 0644); err != nil {
-        c.String(http.StatusInternalServerError, fmt.Sprintf("저장 실패: %v", err))
+    project := projectKey(fullPath)
+    if doRestart == "1" && !canAccess(c, fullPath, permOperate) {
+        c.String(http.StatusForbidden, "재시작 권한이 없습니다. (필요 권한: "+permOperate+")")
         return
     }
-
-    msg := "저장 완료!"
-    // 도커 재시작
+    if doRestart != "1" {
+        // 저장만 할 때도 같은 프로젝트의 작업이 진행 중이면 거부.
+        // 잠금을 잡은 뒤에 파일을 읽어 비교해야 같은 내용에서 시작한 두 저장이 서로 덮어쓰지 않는다.
+        release, busy := jobs.tryLock(project)
+        if release == nil {
+            respondBusy(c, busy)
+            return
+        }
+        defer release()
+    }
+    current, err := ioutil.ReadFile(fullPath)
+    if err != nil {
+        c.String(http.StatusInternalServerError, fmt.Sprintf("파일 읽기 오류: %v", err))
+        return
+    }
+    ev := newAudit(c, "save", p)
     if doRestart == "1" {
-        out, err := dockerComposeRestart(fullPath)
+        ev.Action = "save+restart"
+    }
+    if etag != fileETag(current) {
+        ev.record(errors.New("동시 편집 충돌"))
+        writeConflict(c, fullPath, c.PostForm("base"), content, string(current))
+        return
+    }
+
+    // 저장 전 검증 (force=1 이면 건너뜀)
+    if c.PostForm("force") != "1" {
+        if errs := validateContent(fullPath, content); len(errs) > 0 {
+            ev.record(fmt.Errorf("검증 실패 (%d건)", len(errs)))
+            c.JSON(http.StatusUnprocessableEntity, gin.H{
+                "error":  "검증 실패: 저장하지 않았습니다.",
+                "errors": errs,
+            })
+            return
+        }
+    }
+
+    // 이력 저장 후 새 내용 저장
+    meta := revisionMeta{
+        Author:  currentUser(c).Email,
+        Message: c.PostForm("message"),
+        Action:  "save",
+        Restart: doRestart == "1",
+    }
+
+    if doRestart != "1" {
+        revID, err := revisions.Commit(fullPath, []byte(content), meta)
+        ev.Backup = revID
+        ev.record(err)
         if err != nil {
-            msg += fmt.Sprintf("\n도커 재시작 오류: %v\n출력:%s", err, out)
-        } else {
-            msg += "\n도커 재시작 완료!"
+            c.String(http.StatusInternalServerError, err.Error())
+            return
         }
+        c.Header("ETag", fileETag([]byte(content)))
+        c.String(http.StatusOK, "저장 완료!")
+        return
     }
-    c.String(http.StatusOK, msg)
+
+    // 저장 + 도커 재시작은 프로젝트 잠금을 잡은 백그라운드 작업에서 순서대로 실행
+    opts, err := applyOptionsFromRequest(c, fullPath)
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
+    j := jobs.submit("save+restart", p, project, meta.Author, func(w io.Writer) (err error) {
+        ev := ev
+        ev.Job = jobID(w)
+        defer func() { ev.record(err) }()
+        // 대기하는 동안 다른 작업이 파일을 바꿨다면 덮어쓰지 않음
+        previous, err := ioutil.ReadFile(fullPath)
+        if err != nil {
+            return err
+        }
+        if fileETag(previous) != etag {
+            return fmt.Errorf("대기 중 파일이 변경되어 저장하지 않았습니다. 파일을 다시 불러오세요.")
+        }
+        revID, err := revisions.Commit(fullPath, []byte(content), meta)
+        if err != nil {
+            return err
+        }
+        ev.Backup = revID
+        fmt.Fprintf(w, "저장 완료 (리비전 %s)\n", revID)
+        // 적용 후 정상 기동하지 않으면 저장 전 내용으로 자동 롤백
+        rbID, err := applyWithAutoRollback(w, fullPath, revID, previous, meta.Author, opts)
+        if rbID != "" {
+            rb := ev
+            rb.Action, rb.Backup = "auto-rollback", rbID
+            rb.record(err)
+        }
+        return err
+    })
+    ev.queued(j)
+    c.Header("ETag", fileETag([]byte(content)))
+    respondJob(c, j, "저장 + 도커 재시작 작업을 시작했습니다.")
 }
 
 // 도커 재시작
@@ -356,24 +598,43 @@ func restartDockerAPI(c *gin.Context) {
         c.String(http.StatusBadRequest, "path 필요")
         return
     }
-    fullPath := filepath.Join(baseDir, p)
-    out, err := dockerComposeRestart(fullPath)
+    fullPath, err := resolvePath(p)
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
+    opts, err := applyOptionsFromRequest(c, fullPath)
     if err != nil {
-        c.String(http.StatusInternalServerError, fmt.Sprintf("도커 재시작 오류: %v\n출력:%s", err, out))
+        c.String(http.StatusBadRequest, err.Error())
         return
     }
-    c.String(http.StatusOK, "도커 재시작 완료!")
+    j := submitRestart(c, p, fullPath, opts)
+    respondJob(c, j, "도커 재시작 작업을 시작했습니다.")
+}
+
+// submitRestart: 재시작 작업 등록 (감사 로그 포함, /api/v1 과 공용)
+func submitRestart(c *gin.Context, p, fullPath string, opts applyOptions) *job {
+    ev := newAudit(c, "restart", p)
+    j := jobs.submit("restart", p, projectKey(fullPath), currentUser(c).Email, func(w io.Writer) error {
+        err := applyCompose(w, fullPath, opts)
+        ev := ev
+        ev.Job = jobID(w)
+        ev.record(err)
+        return err
+    })
+    ev.queued(j)
+    return j
 }
 
 // ------------------------------------------------------
 // 6. 백업 로직 (각 디렉토리에 backups/ 폴더)
 // ------------------------------------------------------
 
-func backupFile(filePath string) error {
+func backupFile(filePath string) (string, error) {
     // 원본 파일 읽기
     data, err := ioutil.ReadFile(filePath)
     if err != nil {
-        return err
+        return "", err
     }
 
     // (1) 파일이 있는 디렉토리 내 "backups" 폴더 생성 (없으면)
@@ -381,7 +642,7 @@ func backupFile(filePath string) error {
     localBackupDir := filepath.Join(dirName, "backups")
     if _, err := os.Stat(localBackupDir); os.IsNotExist(err) {
         if err := os.MkdirAll(localBackupDir, 0755); err != nil {
-            return fmt.Errorf("백업 디렉토리 생성 오류: %v", err)
+            return "", fmt.Errorf("백업 디렉토리 생성 오류: %v", err)
         }
     }
 
@@ -392,14 +653,22 @@ func backupFile(filePath string) error {
     timestamp := time.Now().Format("20060102_150405")
     backupName := fmt.Sprintf("%s_%s%s", base, timestamp, ext)
     backupPath := filepath.Join(localBackupDir, backupName)
+    // 같은 초에 여러 번 백업하면 (자동 롤백 등) 덮어쓰지 않도록 번호를 붙임
+    for i := 2; ; i++ {
+        if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+            break
+        }
+        backupName = fmt.Sprintf("%s_%s_%d%s", base, timestamp, i, ext)
+        backupPath = filepath.Join(localBackupDir, backupName)
+    }
 
     // (3) 백업 파일로 저장
     if err := ioutil.WriteFile(backupPath, data, 0644); err != nil {
-        return fmt.Errorf("백업 파일 저장 오류: %v", err)
+        return "", fmt.Errorf("백업 파일 저장 오류: %v", err)
     }
 
     // (4) 백업 정리 (최대 20개)
-    return pruneBackups(localBackupDir, base, ext, 20)
+    return backupName, pruneBackups(localBackupDir, base, ext, 20)
 }
 
 // pruneBackups: localBackupDir에 있는 특정 파일(base+확장자)의 백업이 20개 초과하면 오래된 것부터 삭제
@@ -422,10 +691,11 @@ func pruneBackups(localBackupDir, base, ext string, max int) error {
         return backups[i].ModTime().Before(backups[j].ModTime())
     })
 
-    // max 개수 초과분 삭제
+    // max 개수 초과분 삭제 (메타데이터 포함)
     if len(backups) > max {
         for _, f := range backups[:len(backups)-max] {
             os.Remove(filepath.Join(localBackupDir, f.Name()))
+            os.Remove(filepath.Join(localBackupDir, ".meta", f.Name()+".json"))
         }
     }
     return nil
@@ -435,51 +705,28 @@ func pruneBackups(localBackupDir, base, ext string, max int) error {
 // 7. 백업 목록, 다운로드, 롤백
 // ------------------------------------------------------
 
-// 백업 목록
+// 백업(리비전) 목록: 작성자/메시지/작업/재시작 결과/해시를 포함한 JSON
 func listBackupsAPI(c *gin.Context) {
     p := c.Query("path") // 예: testtt/aaa.yml
     if p == "" {
-        c.String(http.StatusBadRequest, "path 필요")
+        c.JSON(http.StatusBadRequest, gin.H{"error": "path 파라미터 필요"})
         return
     }
-    fullPath := filepath.Join(baseDir, p)
-
-    // 파일명에서 base / ext 추출
-    fileName := filepath.Base(fullPath)
-    ext := filepath.Ext(fileName)
-    base := fileName[0 : len(fileName)-len(ext)]
-
-    // 해당 파일 디렉토리의 backups 폴더
-    dirName := filepath.Dir(fullPath)
-    localBackupDir := filepath.Join(dirName, "backups")
-
-    // backups 폴더 없으면 목록 없음
-    if _, err := os.Stat(localBackupDir); os.IsNotExist(err) {
-        c.Data(http.StatusOK, "text/html; charset=utf-8", []byte("<h3>백업 목록</h3><p>백업 없음</p>"))
+    fullPath, err := resolvePath(p)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
 
-    files, err := ioutil.ReadDir(localBackupDir)
+    revs, err := revisions.List(fullPath)
     if err != nil {
-        c.String(http.StatusInternalServerError, fmt.Sprintf("백업 디렉토리 오류: %v", err))
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("백업 목록 조회 오류: %v", err)})
         return
     }
-
-    var sb strings.Builder
-    sb.WriteString("<h3>백업 목록</h3><ul>")
-    for _, f := range files {
-        if !f.IsDir() && strings.HasPrefix(f.Name(), base+"_") {
-            // 다운로드/롤백 링크를 만들어서 반환
-            sb.WriteString(fmt.Sprintf(`
-<li>%s
-  <a href="/console/api/backup/download?backupfile=%s&target=%s" target="_blank">[다운로드]</a>
-  <button onclick="rollbackBackup('%s')">롤백</button>
-</li>
-`, f.Name(), f.Name(), p, f.Name()))
-        }
+    if revs == nil {
+        revs = []revision{}
     }
-    sb.WriteString("</ul>")
-    c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(sb.String()))
+    c.JSON(http.StatusOK, revs)
 }
 
 // 백업 다운로드
@@ -491,22 +738,26 @@ func downloadBackupAPI(c *gin.Context) {
         return
     }
 
-    // target 파일의 디렉토리에 있는 backups 폴더
-    fullPath := filepath.Join(baseDir, target)
-    dirName := filepath.Dir(fullPath)
-    localBackupDir := filepath.Join(dirName, "backups")
-    backupPath := filepath.Join(localBackupDir, bf)
-
-    f, err := os.Open(backupPath)
+    fullPath, err := resolvePath(target)
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
+    data, err := revisions.Read(fullPath, bf)
     if err != nil {
         c.String(http.StatusInternalServerError, fmt.Sprintf("백업 파일 열기 실패: %v", err))
         return
     }
-    defer f.Close()
 
-    c.Header("Content-Disposition", "attachment; filename="+bf)
-    c.Header("Content-Type", "application/octet-stream")
-    io.Copy(c.Writer, f)
+    // git 리비전처럼 확장자가 없는 ID 는 [기존파일명_ID.확장자] 로 내려준다
+    name := bf
+    if filepath.Ext(bf) == "" {
+        fileName := filepath.Base(fullPath)
+        ext := filepath.Ext(fileName)
+        name = fmt.Sprintf("%s_%s%s", fileName[0:len(fileName)-len(ext)], bf, ext)
+    }
+    c.Header("Content-Disposition", "attachment; filename="+name)
+    c.Data(http.StatusOK, "application/octet-stream", data)
 }
 
 func rollbackFileAPI(c *gin.Context) {
@@ -517,37 +768,69 @@ func rollbackFileAPI(c *gin.Context) {
         return
     }
 
-    // target 파일 경로 및 backupPath
-    fullPath := filepath.Join(baseDir, target)
-    dirName := filepath.Dir(fullPath)
-    localBackupDir := filepath.Join(dirName, "backups")
-    backupPath := filepath.Join(localBackupDir, bf)
-
-    // ========== 1) 롤백 전, 현재 파일을 새로 백업해 둔다. ==========
-    if err := backupFile(fullPath); err != nil {
-        c.String(http.StatusInternalServerError, fmt.Sprintf("롤백 실패(현재 파일 백업 중 오류): %v", err))
+    fullPath, err := resolvePath(target)
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
         return
     }
 
-    // ========== 2) 과거 백업본(rollback 대상)을 읽어서, 현재 파일에 덮어쓰기 ==========
-    data, err := ioutil.ReadFile(backupPath)
-    if err != nil {
+    // ========== 1) 과거 백업본(rollback 대상)을 읽을 수 있는지 먼저 확인 ==========
+    if _, err := revisions.Read(fullPath, bf); err != nil {
         c.String(http.StatusInternalServerError, fmt.Sprintf("백업 파일 읽기 실패: %v", err))
         return
     }
-    if err := ioutil.WriteFile(fullPath, data, 0644); err != nil {
-        c.String(http.StatusInternalServerError, fmt.Sprintf("롤백 실패(덮어쓰기 오류): %v", err))
+    opts, err := applyOptionsFromRequest(c, fullPath)
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
         return
     }
+    j := submitRollback(c, target, fullPath, bf, c.PostForm("message"), opts)
+    respondJob(c, j, "롤백(현재 상태 백업 후 과거 버전 복원) 및 도커 재시작 작업을 시작했습니다.")
+}
+
+// submitRollback: 롤백 작업 등록 (감사 로그 포함, /api/v1 과 공용)
+func submitRollback(c *gin.Context, target, fullPath, bf, message string, opts applyOptions) *job {
+    if message == "" {
+        message = "롤백: " + bf
+    }
+    meta := revisionMeta{Author: currentUser(c).Email, Message: message, Action: "rollback", Restart: true}
+
+    // ========== 2) 프로젝트 잠금을 잡은 뒤 현재 파일을 이력으로 남기고 백업본으로 덮어쓰기 ==========
+    // ========== 3) Docker Compose 재시작 (백그라운드 작업) ==========
+    ev := newAudit(c, "rollback", target)
+    ev.Backup = bf
+    j := jobs.submit("rollback", target, projectKey(fullPath), meta.Author, func(w io.Writer) (err error) {
+        ev := ev
+        ev.Job = jobID(w)
+        defer func() { ev.record(err) }()
+        data, err := revisions.Read(fullPath, bf)
+        if err != nil {
+            return fmt.Errorf("백업 파일 읽기 실패: %v", err)
+        }
+        fmt.Fprintf(w, "롤백 대상: %s\n", bf)
+        _, err = restoreAndApply(w, fullPath, data, meta, opts)
+        return err
+    })
+    ev.queued(j)
+    return j
+}
 
-    // ========== 3) Docker Compose 재시작 ==========
-    out, err := dockerComposeRestart(fullPath)
+// restartResultText: 리비전 메타데이터에 남길 재시작 결과
+func restartResultText(err error) string {
     if err != nil {
-        c.String(http.StatusInternalServerError, fmt.Sprintf("도커 재시작 오류: %v\n출력:%s", err, out))
-        return
+        return fmt.Sprintf("실패: %v", err)
     }
+    return "성공"
+}
 
-    c.String(http.StatusOK, "롤백(현재 상태 백업 후 과거 버전 복원) 및 도커 재시작 완료!")
+// recordRestartResult: 리비전 메타데이터에 재시작 결과 기록 (실패해도 요청은 계속 진행)
+func recordRestartResult(fullPath, revID, result string) {
+    if revID == "" {
+        return
+    }
+    if err := revisions.SetRestartResult(fullPath, revID, result); err != nil {
+        log.Printf("[경고] 재시작 결과 기록 실패(%s): %v", revID, err)
+    }
 }
 
 // ------------------------------------------------------
@@ -560,12 +843,20 @@ func createDirectoryAPI(c *gin.Context) {
         c.String(http.StatusBadRequest, "dirname 필요")
         return
     }
-    targetPath := filepath.Join(baseDir, dirname)
+    targetPath, err := resolvePath(dirname)
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
+    ev := newAudit(c, "create-dir", dirname)
     if _, err := os.Stat(targetPath); !os.IsNotExist(err) {
+        ev.record(errors.New("이미 존재하는 디렉토리"))
         c.String(http.StatusBadRequest, "이미 존재하는 디렉토리")
         return
     }
-    if err := os.Mkdir(targetPath, 0755); err != nil {
+    err = os.Mkdir(targetPath, 0755)
+    ev.record(err)
+    if err != nil {
         c.String(http.StatusInternalServerError, fmt.Sprintf("디렉토리 생성 오류: %v", err))
         return
     }
@@ -579,14 +870,26 @@ func createFileAPI(c *gin.Context) {
         c.String(http.StatusBadRequest, "dir, filename 필요")
         return
     }
-    fullDir := filepath.Join(baseDir, dir)
-    target := filepath.Join(fullDir, filename)
+    // 파일명은 디렉토리 구분자 없는 단일 이름만 허용
+    if !validName(filename) {
+        c.String(http.StatusBadRequest, "잘못된 파일명")
+        return
+    }
+    target, err := resolvePath(filepath.Join(dir, filename))
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
+    ev := newAudit(c, "create-file", filepath.Join(dir, filename))
     if _, err := os.Stat(target); !os.IsNotExist(err) {
+        ev.record(errors.New("이미 존재하는 파일"))
         c.String(http.StatusBadRequest, "이미 존재하는 파일")
         return
     }
     // 빈 파일 생성
-    if err := ioutil.WriteFile(target, []byte(""), 0644); err != nil {
+    err = ioutil.WriteFile(target, []byte(""), 0644)
+    ev.record(err)
+    if err != nil {
         c.String(http.StatusInternalServerError, fmt.Sprintf("파일 생성 오류: %v", err))
         return
     }
@@ -601,46 +904,71 @@ func adminPage(c *gin.Context) {
     var userList []map[string]string
     for _, u := range users {
         userList = append(userList, map[string]string{
-            "Email": u.Email,
-            "Role":  u.Role,
+            "Email":   u.Email,
+            "Role":    u.Role,
+            "Grants":  formatGrants(u.Grants),
+            "TOTP":    boolText(u.TOTPSecret != ""),
+            "Require": boolText(u.Require2FA),
         })
     }
+    sort.Slice(userList, func(i, j int) bool { return userList[i]["Email"] < userList[j]["Email"] })
     c.HTML(http.StatusOK, "admin.html", gin.H{
-        "Users": userList,
+        "Users":     userList,
+        "Roles":     roles,
+        "Projects":  listProjects(),
+        "Locked":    lockedAccounts(),
+        "CSRFToken": csrfToken(c),
     })
 }
 
+// boolText: 템플릿용 체크 상태 ("on" 또는 "")
+func boolText(b bool) string {
+    if b {
+        return "on"
+    }
+    return ""
+}
+
 func updateUserRole(c *gin.Context) {
     email := c.PostForm("email")
     role := c.PostForm("role")
-    if email == "" || (role != "admin" && role != "none") {
+    if email == "" || !validRole(role) {
         c.String(http.StatusBadRequest, "잘못된 요청")
         return
     }
-    u, ok := users[email]
-    if !ok {
-        c.String(http.StatusBadRequest, "사용자를 찾을 수 없습니다.")
+    if status, err := changeUserRole(c, email, role); err != nil {
+        c.String(status, err.Error())
         return
     }
-    u.Role = role
-    saveAccounts()
     c.String(http.StatusOK, "권한이 업데이트되었습니다. <a href='/console/admin'>돌아가기</a>")
 }
 
-func adminOnly(fn gin.HandlerFunc) gin.HandlerFunc {
-    return func(c *gin.Context) {
-        u := currentUser(c)
-        if u == nil || u.Role != "admin" {
-            // 어드민이 아닌 경우
-            msg := "해당 콘솔의 사용을 위해서는 관리자 권한이 필요합니다.\n" +
-                   "어드민 이메일: " + firstRegisteredUserEmail
-            c.String(http.StatusForbidden, msg)
-            c.Abort()
-            return
-        }
-        // 어드민이면 정상 진입
-        fn(c)
+// changeUserRole: 전역 역할 변경 (감사 로그, 안내 메일 포함). 실패하면 응답할 상태 코드와 오류
+func changeUserRole(c *gin.Context, email, role string) (int, error) {
+    u, ok := users[email]
+    if !ok {
+        return http.StatusNotFound, errors.New("사용자를 찾을 수 없습니다.")
+    }
+    ev := newAudit(c, "role", email)
+    oldRole := u.Role
+    ev.Detail = oldRole + " -> " + role
+    // 마지막 어드민의 권한은 내릴 수 없음 (아무도 관리할 수 없게 되는 것 방지)
+    if u.Role == roleAdmin && role != roleAdmin && adminCount() == 1 {
+        ev.record(errors.New("마지막 어드민"))
+        return http.StatusConflict, errors.New("마지막 어드민의 권한은 변경할 수 없습니다.")
     }
+    u.Role = role
+    err := saveAccounts()
+    ev.record(err)
+    if err != nil {
+        return http.StatusInternalServerError, fmt.Errorf("계정 저장 오류: %v", err)
+    }
+    if oldRole != role {
+        notify([]string{email}, mailRoleChanged, map[string]interface{}{
+            "Email": email, "OldRole": oldRole, "NewRole": role, "ChangedBy": ev.User,
+        })
+    }
+    return http.StatusOK, nil
 }
 
 
@@ -650,36 +978,63 @@ func adminOnly(fn gin.HandlerFunc) gin.HandlerFunc {
 // 10. Docker Compose 재시작 로직
 // ------------------------------------------------------
 
-// dockerComposeRestart: "docker-compose -f [파일] down; sleep 2; up -d" 실행
-func dockerComposeRestart(filePath string) (string, error) {
+// dockerComposeRestart: "docker-compose -f [파일] pull; down; sleep 2; up -d" 실행
+// 출력은 w 로 바로 기록되므로 작업 화면에서 진행 상황을 볼 수 있다.
+// errPullFailed: 사전 pull 실패 (down 전에 중단했으므로 스택은 건드리지 않은 상태)
+var errPullFailed = errors.New("이미지 pull 실패로 재시작을 중단했습니다")
+
+func dockerComposeRestart(w io.Writer, filePath string) error {
     if composeCommand == "" {
-        return "", fmt.Errorf("docker compose 명령이 감지되지 않았습니다.")
+        return fmt.Errorf("docker compose 명령이 감지되지 않았습니다.")
     }
 
-    dir := filepath.Dir(filePath)
-    fileBase := filepath.Base(filePath)
+    // 이미지를 먼저 받아 둔다. 실패하면 실행 중인 컨테이너는 건드리지 않고 중단
+    if err := pullImages(w, filePath); err != nil {
+        return fmt.Errorf("%w (실행 중인 컨테이너는 그대로): %v", errPullFailed, err)
+    }
 
-    // 예) composeCommand = "docker compose"
-    // -> parts[0] = "docker", parts[1] = "compose"
-    parts := strings.Split(composeCommand, " ")
     // down 명령
-    argsDown := append(parts[1:], "-f", fileBase, "down")
-    cmdDown := exec.Command(parts[0], argsDown...)
-    cmdDown.Dir = dir
-    outDown, errDown := cmdDown.CombinedOutput()
-    if errDown != nil {
-        return string(outDown), errDown
+    if err := runCompose(w, filePath, "down"); err != nil {
+        return err
     }
 
     time.Sleep(2 * time.Second)
 
     // up -d 명령
-    argsUp := append(parts[1:], "-f", fileBase, "up", "-d")
-    cmdUp := exec.Command(parts[0], argsUp...)
-    cmdUp.Dir = dir
-    outUp, errUp := cmdUp.CombinedOutput()
+    return runCompose(w, filePath, "up", "-d")
+}
 
-    return string(outDown) + "\n" + string(outUp), errUp
+// pullImages: compose pull 을 한 번 실행하며 진행 상황을 w 에 기록
+// build 가 있는 서비스(로컬에서 빌드하는 이미지)는 --ignore-buildable 로 건너뛴다.
+// 이 옵션이 없는 이전 compose 에서는 --ignore-pull-failures 로 받을 수 있는 것만 받는다.
+func pullImages(w io.Writer, filePath string) error {
+    fmt.Fprintln(w, "[pull] 이미지를 먼저 받습니다.")
+    var out bytes.Buffer
+    err := runCompose(io.MultiWriter(w, &out), filePath, "pull", "--ignore-buildable")
+    if err != nil && unknownOption(out.String()) {
+        fmt.Fprintln(w, "[pull] 이 compose 는 --ignore-buildable 을 지원하지 않아 --ignore-pull-failures 로 다시 받습니다.")
+        err = runCompose(w, filePath, "pull", "--ignore-pull-failures")
+    }
+    if err != nil {
+        return err
+    }
+    fmt.Fprintln(w, "[pull] 완료")
+    return nil
+}
+
+// unknownOption: compose 가 모르는 옵션이라고 답했는지 (v2: "unknown flag", v1: "no such option" 또는 사용법 출력)
+func unknownOption(out string) bool {
+    return strings.Contains(out, "unknown flag") || strings.Contains(out, "no such option") ||
+        strings.Contains(out, "Usage:")
+}
+
+// runCompose: compose 명령을 실행하며 명령줄과 출력을 w 에 기록
+func runCompose(w io.Writer, filePath string, args ...string) error {
+    fmt.Fprintf(w, "\n$ %s -f %s %s\n", composeCommand, filepath.Base(filePath), strings.Join(args, " "))
+    cmd := composeExec(filePath, args...)
+    cmd.Stdout = w
+    cmd.Stderr = w
+    return cmd.Run()
 }
 
 
@@ -701,16 +1056,65 @@ func runServer() {
     }
     serverPort := ":" + portStr
 
+    // 적용 후 헬스 확인 시간
+    loadHealthWindow()
+
+    // 로그인 실패 잠금 설정
+    loadLoginGuard()
+
+    // 감사 로그 파일
+    if f := os.Getenv("audit_log"); f != "" {
+        auditFile = f
+    }
+
+    // 메일 발송 (smtp_host 가 없으면 로그만 남김)
+    publicURL = strings.TrimRight(os.Getenv("public_url"), "/")
+    if publicURL == "" {
+        publicURL = "http://localhost:" + portStr
+    }
+    smtpCfg, smtpEnabled, err := loadSMTPConfig()
+    if err != nil {
+        log.Fatalf("[에러] SMTP 설정 오류: %v\n", err)
+    }
+    if mails, err = newMailer(smtpCfg, smtpEnabled); err != nil {
+        log.Fatalf("[에러] %v\n", err)
+    }
+    if !smtpEnabled {
+        log.Println("[경고] smtp_host 가 설정되지 않아 메일은 발송하지 않고 로그로만 남깁니다.")
+    }
+
+    // 세션 키 및 쿠키 설정
+    if sessionCfg, err = loadSessionConfig(); err != nil {
+        log.Fatalf("[에러] 세션 설정 오류: %v\n", err)
+    }
+
     // 사용자 로드
     if err := loadAccounts(); err != nil {
         log.Println("사용자 정보 로드 오류:", err)
     }
 
+    // API 토큰 로드
+    if err := loadTokens(); err != nil {
+        log.Println("API 토큰 로드 오류:", err)
+    }
+
+    // 등록 프로젝트 로드
+    if err := loadProjects(); err != nil {
+        log.Println("프로젝트 정보 로드 오류:", err)
+    }
+
     // 디렉토리 준비
     if _, err := os.Stat(baseDir); os.IsNotExist(err) {
         os.Mkdir(baseDir, 0755)
     }
 
+    // 리비전 저장소 (기본: backups/ 파일 복사, revision_backend="git" 이면 git 커밋)
+    revStore, err := newRevisionStore(os.Getenv("revision_backend"))
+    if err != nil {
+        log.Fatalf("[에러] 리비전 저장소 초기화 실패: %v\n", err)
+    }
+    revisions = revStore
+
 
     // ★ docker compose vs docker-compose 명령 감지 ★
     cmd, err := detectDockerComposeCommand()
@@ -722,50 +1126,127 @@ func runServer() {
 
 
     // Gin 설정
+    r, err := newRouter()
+    if err != nil {
+        log.Fatalf("[에러] %v\n", err)
+    }
+
+    log.Printf("서버가 포트 %s 로 시작됩니다.\n", serverPort)
+    if err := r.Run(serverPort); err != nil {
+        log.Fatalf("서버 실행 중 오류: %v", err)
+    }
+}
+
+// newRouter: 미들웨어와 모든 라우트를 등록한 gin 엔진 (세션 설정 등은 runServer 에서 먼저 읽는다)
+func newRouter() (*gin.Engine, error) {
     r := gin.Default()
+    // 클라이언트 IP (로그인 제한, 감사 로그) 는 trusted_proxies 로 지정한 프록시의 X-Forwarded-For 만 믿는다
+    if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+        return nil, fmt.Errorf("trusted_proxies 설정 오류: %v", err)
+    }
     r.LoadHTMLGlob("templates/*.html")
 
-    // 세션
-    store := cookie.NewStore([]byte("secret"))
-    r.Use(sessions.Sessions("mysession", store))
+    // 세션 (키는 .env, 없으면 생성해 저장)
+    r.Use(sessions.Sessions("mysession", newSessionStore(sessionCfg)))
+
+    // 모든 POST 요청은 CSRF 토큰 필요 (csrf.go)
+    r.Use(csrfProtect())
 
     // 로그인 불필요 라우트
     r.GET("/", landingPage)
     r.POST("/login", doLogin)
+    r.GET("/login/2fa", showLogin2FA)
+    r.POST("/login/2fa", doLogin2FA)
     r.GET("/register", showRegister)
     r.POST("/register", doRegister)
+    r.GET("/password/forgot", showForgotPassword)
+    r.POST("/password/forgot", doForgotPassword)
+    r.GET("/password/reset", showResetPassword)
+    r.POST("/password/reset", doResetPassword)
 
     // 로그인 필요한 라우트
     auth := r.Group("/")
     auth.Use(AuthRequired()) // 로그인 필요
+    auth.Use(require2FAEnrollment()) // 어드민이 요구한 2단계 인증 등록 전에는 등록 페이지만
     {
-       // ★ adminOnly 추가 ★
-       auth.GET("/console", adminOnly(consolePage))
+       // ★ 역할별 권한 검사 (rbac.go) ★
+       auth.GET("/console", requirePermission(permRead), consolePage)
 
        // 디렉토리/파일 관련 API
-       auth.GET("/console/api/dir", adminOnly(listDirectoriesAPI))
-       auth.GET("/console/api/files", adminOnly(listFilesAPI))
-       auth.GET("/console/api/file", adminOnly(getFileContentAPI))
-       auth.POST("/console/api/file", adminOnly(saveFileAPI))
-       auth.POST("/console/api/restart", adminOnly(restartDockerAPI))
-       auth.GET("/console/api/backups", adminOnly(listBackupsAPI))
-       auth.GET("/console/api/backup/download", adminOnly(downloadBackupAPI))
-       auth.POST("/console/api/backup/rollback", adminOnly(rollbackFileAPI))
-       auth.POST("/console/api/dir/create", adminOnly(createDirectoryAPI))
-       auth.POST("/console/api/file/create", adminOnly(createFileAPI))
-
-       // 어드민 페이지도 당연히 adminOnly
-       auth.GET("/console/admin", adminOnly(adminPage))
-       auth.POST("/console/admin/role", adminOnly(updateUserRole))
-
-       // 로그아웃 등은 adminOnly 아닙니다 (모두 가능)
+       auth.GET("/console/api/dir", requirePermission(permRead), listDirectoriesAPI)
+       auth.GET("/console/api/files", requirePermission(permRead), listFilesAPI)
+       auth.GET("/console/api/file", requirePermission(permRead), getFileContentAPI)
+       auth.POST("/console/api/file", requirePermission(permEdit), saveFileAPI)
+       auth.POST("/console/api/restart", requirePermission(permOperate), restartDockerAPI)
+       auth.GET("/console/api/backups", requirePermission(permRead), listBackupsAPI)
+       auth.GET("/console/api/backup/download", requirePermission(permRead), downloadBackupAPI)
+       auth.GET("/console/api/diff", requirePermission(permRead), diffAPI)
+       auth.POST("/console/api/diff", requirePermission(permRead), diffPendingAPI)
+       auth.POST("/console/api/backup/rollback", requirePermission(permOperate), rollbackFileAPI)
+       auth.POST("/console/api/dir/create", requirePermission(permEdit), createDirectoryAPI)
+       auth.POST("/console/api/file/create", requirePermission(permEdit), createFileAPI)
+
+       // 서비스 상태 조회
+       auth.GET("/console/api/status", requirePermission(permRead), statusAPI)
+       auth.GET("/console/api/status/overview", requirePermission(permRead), statusOverviewAPI)
+       auth.GET("/console/api/logs", requirePermission(permRead), streamLogsAPI)
+       auth.POST("/console/api/service/action", requirePermission(permOperate), serviceActionAPI)
+       auth.GET("/console/api/project/settings", requirePermission(permRead), getProjectSettingsAPI)
+       auth.GET("/console/api/permissions", requirePermission(permRead), permissionsAPI)
+       auth.POST("/console/api/project/settings", requirePermission(permEdit), saveProjectSettingsAPI)
+
+       // 백그라운드 작업
+       auth.GET("/console/api/jobs", requirePermission(permRead), listJobsAPI)
+       auth.GET("/console/api/jobs/:id", requirePermission(permRead), getJobAPI)
+       auth.GET("/console/api/jobs/:id/stream", requirePermission(permRead), streamJobAPI)
+
+       // 어드민 페이지는 admin 권한
+       auth.GET("/console/admin", requirePermission(permAdmin), adminPage)
+       auth.POST("/console/admin/role", requirePermission(permAdmin), updateUserRole)
+       auth.POST("/console/admin/grants", requirePermission(permAdmin), updateUserGrants)
+       auth.POST("/console/admin/unlock", requirePermission(permAdmin), unlockAccount)
+       auth.POST("/console/admin/require2fa", requirePermission(permAdmin), updateRequire2FA)
+       auth.POST("/console/admin/reset2fa", requirePermission(permAdmin), reset2FA)
+       auth.GET("/console/admin/audit", requirePermission(permAdmin), auditPage)
+       auth.GET("/console/admin/audit.csv", requirePermission(permAdmin), auditCSV)
+       auth.GET("/console/admin/projects", requirePermission(permAdmin), listProjectsAPI)
+       auth.POST("/console/admin/projects", requirePermission(permAdmin), saveProjectAPI)
+       auth.POST("/console/admin/projects/delete", requirePermission(permAdmin), deleteProjectAPI)
+
+       // 로그아웃, 본인 2단계 인증 설정은 권한 검사 없음 (모두 가능)
+       // 2단계 인증 설정은 브라우저 로그인으로만 (토큰이 새면 소유자를 잠글 수 있으므로)
        auth.GET("/logout", doLogout)
-    }
-
-    log.Printf("서버가 포트 %s 로 시작됩니다.\n", serverPort)
-    if err := r.Run(serverPort); err != nil {
-        log.Fatalf("서버 실행 중 오류: %v", err)
-    }
+       auth.GET("/account/2fa", requireSession(), twoFactorPage)
+       auth.GET("/account/2fa/qr.png", requireSession(), twoFactorQRCode)
+       auth.POST("/account/2fa/enable", requireSession(), enableTwoFactor)
+       auth.POST("/account/2fa/recovery", requireSession(), regenerateRecoveryCodes)
+       auth.POST("/account/2fa/disable", requireSession(), disableTwoFactor)
+
+       // API 토큰 관리는 브라우저 로그인으로만
+       auth.GET("/account/tokens", requireSession(), tokensPage)
+       auth.POST("/account/tokens", requireSession(), createTokenAPI)
+       auth.POST("/account/tokens/revoke", requireSession(), revokeTokenAPI)
+    }
+
+    // ★ 버전 있는 JSON API (apiv1.go). 오류는 {"error":{"code","message"}} ★
+    r.StaticFile(apiV1Prefix+"/openapi.json", openAPIFile) // 문서는 로그인 없이 공개
+    v1 := r.Group(apiV1Prefix)
+    v1.Use(apiAuthRequired())
+    {
+       v1.GET("/directories", apiRequire(permRead), apiListDirectories)
+       v1.GET("/files", apiRequire(permRead), apiListFiles)
+       v1.GET("/file", apiRequire(permRead), apiGetFile)
+       v1.PUT("/file", apiRequire(permEdit), apiSaveFile)
+       v1.GET("/backups", apiRequire(permRead), apiListBackups)
+       v1.POST("/backups/rollback", apiRequire(permOperate), apiRollback)
+       v1.GET("/status", apiRequire(permRead), apiStatus)
+       v1.POST("/restart", apiRequire(permOperate), apiRestart)
+       v1.GET("/jobs/:id", apiRequire(permRead), apiGetJob)
+       v1.GET("/users", apiRequire(permAdmin), apiListUsers)
+       v1.PUT("/users/:email/role", apiRequire(permAdmin), apiUpdateUserRole)
+    }
+    r.NoRoute(apiNotFound)
+    return r, nil
 }
 
 // ------------------------------------------------------
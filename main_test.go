@@ -0,0 +1,58 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "testing"
+
+    "github.com/gin-contrib/sessions"
+    "github.com/gin-contrib/sessions/cookie"
+    "github.com/gin-gonic/gin"
+)
+
+// 여러 테스트 파일에서 함께 쓰는 도우미
+
+// 이 헤더가 있으면 testRouter 가 그 이메일로 로그인한 세션처럼 처리한다
+const testUserHeader = "X-Test-User"
+
+// testRouter: 세션 미들웨어만 붙인 gin 엔진
+func testRouter() *gin.Engine {
+    gin.SetMode(gin.TestMode)
+    r := gin.New()
+    r.Use(sessions.Sessions("test", cookie.NewStore([]byte("0123456789abcdef0123456789abcdef"))))
+    r.Use(func(c *gin.Context) {
+        if email := c.GetHeader(testUserHeader); email != "" {
+            sessions.Default(c).Set(sessionUserKey, email)
+        }
+    })
+    return r
+}
+
+// setTestUsers: 전역 users 를 주어진 사용자로 바꾸고 테스트가 끝나면 되돌린다
+func setTestUsers(t *testing.T, list ...*User) {
+    old := users
+    users = make(map[string]*User)
+    for _, u := range list {
+        users[u.Email] = u
+    }
+    t.Cleanup(func() { users = old })
+}
+
+// testRequest: method 요청을 보내고 응답을 돌려준다. form 이 있으면 POST 폼 본문으로 보낸다.
+func testRequest(r http.Handler, method, target string, form url.Values, header map[string]string) *httptest.ResponseRecorder {
+    var req *http.Request
+    if form != nil {
+        req = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+        req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    } else {
+        req = httptest.NewRequest(method, target, nil)
+    }
+    for k, v := range header {
+        req.Header.Set(k, v)
+    }
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    return w
+}
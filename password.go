@@ -0,0 +1,147 @@
+package main
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "golang.org/x/crypto/bcrypt"
+)
+
+// ------------------------------------------------------
+// 27. 비밀번호 재설정 (메일 링크)
+// ------------------------------------------------------
+
+// 재설정 링크 유효 시간
+const resetTokenTTL = 30 * time.Minute
+
+// 같은 계정으로 재설정 메일을 다시 보내기까지 최소 간격 (메일 폭주 방지)
+const resetResendInterval = time.Minute
+
+type resetToken struct {
+    Email   string
+    Issued  time.Time
+    Expires time.Time
+}
+
+// 발급된 토큰 (키: 토큰의 SHA-256, 원문은 메일에만 있음). 서버 재시작 시 모두 무효
+var (
+    resetMu     sync.Mutex
+    resetTokens = make(map[string]resetToken)
+)
+
+func hashResetToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+// issueResetToken: email 의 기존 토큰을 지우고 새 토큰 발급. 최근에 발급했다면 "" 반환
+func issueResetToken(email string) string {
+    resetMu.Lock()
+    defer resetMu.Unlock()
+    now := time.Now()
+    for key, t := range resetTokens {
+        if t.Email == email && now.Sub(t.Issued) < resetResendInterval {
+            return ""
+        }
+        if t.Email == email || now.After(t.Expires) {
+            delete(resetTokens, key)
+        }
+    }
+    token := randomHex(32)
+    resetTokens[hashResetToken(token)] = resetToken{Email: email, Issued: now, Expires: now.Add(resetTokenTTL)}
+    return token
+}
+
+// lookupResetToken: 유효한 토큰이면 해당 이메일
+func lookupResetToken(token string) (string, bool) {
+    resetMu.Lock()
+    defer resetMu.Unlock()
+    t, ok := resetTokens[hashResetToken(token)]
+    if !ok || time.Now().After(t.Expires) {
+        return "", false
+    }
+    return t.Email, true
+}
+
+// consumeResetToken: 사용한 토큰 삭제 (한 번만 사용 가능)
+func consumeResetToken(token string) {
+    resetMu.Lock()
+    defer resetMu.Unlock()
+    delete(resetTokens, hashResetToken(token))
+}
+
+func showForgotPassword(c *gin.Context) {
+    c.HTML(http.StatusOK, "forgot.html", gin.H{"CSRFToken": csrfToken(c)})
+}
+
+// 재설정 메일 요청. 가입 여부가 드러나지 않도록 항상 같은 응답을 준다.
+func doForgotPassword(c *gin.Context) {
+    email := c.PostForm("email")
+    ev := auditEntry{User: email, IP: c.ClientIP(), Action: "password-reset", Detail: "request"}
+    if _, ok := users[email]; ok {
+        if token := issueResetToken(email); token != "" {
+            notify([]string{email}, mailPasswordReset, map[string]interface{}{
+                "Email":   email,
+                "Token":   token,
+                "Minutes": int(resetTokenTTL.Minutes()),
+            })
+            ev.record(nil)
+        } else {
+            ev.record(errors.New("재발송 간격 미만"))
+        }
+    } else {
+        ev.record(errors.New("등록되지 않은 이메일"))
+    }
+    c.String(http.StatusOK, "가입된 이메일이라면 비밀번호 재설정 링크를 보냈습니다. 메일함을 확인하세요. <a href='/'>돌아가기</a>")
+}
+
+func showResetPassword(c *gin.Context) {
+    token := c.Query("token")
+    if _, ok := lookupResetToken(token); !ok {
+        c.String(http.StatusBadRequest, "재설정 링크가 잘못되었거나 만료되었습니다. <a href='/password/forgot'>다시 요청</a>")
+        return
+    }
+    c.HTML(http.StatusOK, "reset.html", gin.H{"Token": token, "CSRFToken": csrfToken(c)})
+}
+
+func doResetPassword(c *gin.Context) {
+    token := c.PostForm("token")
+    pw := c.PostForm("password")
+    email, ok := lookupResetToken(token)
+    if !ok {
+        c.String(http.StatusBadRequest, "재설정 링크가 잘못되었거나 만료되었습니다. <a href='/password/forgot'>다시 요청</a>")
+        return
+    }
+    if pw == "" {
+        c.String(http.StatusBadRequest, "새 비밀번호가 필요합니다.")
+        return
+    }
+    u, ok := users[email]
+    if !ok {
+        consumeResetToken(token)
+        c.String(http.StatusBadRequest, "사용자를 찾을 수 없습니다.")
+        return
+    }
+    hashed, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.DefaultCost)
+    if err != nil {
+        c.String(http.StatusInternalServerError, "비밀번호 해싱 오류")
+        return
+    }
+    ev := auditEntry{User: email, IP: c.ClientIP(), Action: "password-reset", Detail: "reset"}
+    u.Password = string(hashed)
+    err = saveAccounts()
+    ev.record(err)
+    if err != nil {
+        c.String(http.StatusInternalServerError, "계정 저장 오류: "+err.Error())
+        return
+    }
+    consumeResetToken(token)
+    // 메일로 본인 확인을 마쳤으므로 로그인 실패로 잠긴 계정도 풀어 준다
+    clearAccountLock(email)
+    c.String(http.StatusOK, "비밀번호가 변경되었습니다. <a href='/'>로그인</a>")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// ------------------------------------------------------
+// 19. 경로 검증 (baseDir 밖으로 벗어나는 경로 차단)
+// ------------------------------------------------------
+
+var errPathOutside = errors.New("허용되지 않은 경로입니다.")
+
+// evalExisting: 존재하는 가장 긴 상위 경로까지 심볼릭 링크를 해석하고 나머지를 이어붙인다.
+// (새로 만들 파일/디렉토리처럼 아직 없는 경로도 검사할 수 있도록)
+func evalExisting(p string) (string, error) {
+    var rest []string
+    cur := p
+    for {
+        resolved, err := filepath.EvalSymlinks(cur)
+        if err == nil {
+            for i := len(rest) - 1; i >= 0; i-- {
+                resolved = filepath.Join(resolved, rest[i])
+            }
+            return resolved, nil
+        }
+        if !os.IsNotExist(err) {
+            return "", err
+        }
+        parent := filepath.Dir(cur)
+        if parent == cur {
+            return "", err
+        }
+        rest = append(rest, filepath.Base(cur))
+        cur = parent
+    }
+}
+
+// within: p 가 root 자신이거나 root 하위인지 확인
+func within(root, p string) bool {
+    rel, err := filepath.Rel(root, p)
+    if err != nil {
+        return false
+    }
+    return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// resolveIn: root 기준 상대경로 rel 을 정규화(심볼릭 링크 포함)하고 root 밖이면 오류
+func resolveIn(root, rel string) (string, error) {
+    if rel == "" || strings.ContainsRune(rel, 0) || filepath.IsAbs(rel) {
+        return "", errPathOutside
+    }
+    rootAbs, err := filepath.Abs(root)
+    if err != nil {
+        return "", err
+    }
+    rootReal, err := filepath.EvalSymlinks(rootAbs)
+    if err != nil {
+        return "", err
+    }
+    joined := filepath.Join(rootReal, rel)
+    if !within(rootReal, joined) {
+        return "", errPathOutside
+    }
+    real, err := evalExisting(joined)
+    if err != nil {
+        return "", err
+    }
+    if !within(rootReal, real) {
+        return "", errPathOutside
+    }
+    return real, nil
+}
+
+// resolvePath: 웹 요청으로 받은 경로(예: "testtt/aaa.yml")를 baseDir 안의 실제 경로로 변환
+// "@이름/..." 형태는 등록된 프로젝트 루트 안의 경로로 변환한다.
+// 모든 파일시스템 핸들러는 filepath.Join(baseDir, ...) 대신 이 함수를 사용한다.
+func resolvePath(rel string) (string, error) {
+    if strings.HasPrefix(rel, projectPrefix) {
+        return resolveProjectPath(rel)
+    }
+    p, err := resolveIn(baseDir, rel)
+    if err != nil {
+        return "", err
+    }
+    // baseDir 자체는 파일/디렉토리 대상이 될 수 없음
+    if root, _ := resolveIn(baseDir, "."); p == root {
+        return "", errPathOutside
+    }
+    return p, nil
+}
+
+// validName: 디렉토리 구분자 없이 단일 이름인지 확인 (파일명, 백업 파일명 등)
+func validName(name string) bool {
+    return name != "" && name != "." && name != ".." &&
+        !strings.ContainsAny(name, `/\`) && !strings.ContainsRune(name, 0)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// 테스트용 트리
+//   base/app/docker-compose.yml
+//   base/..a/docker-compose.yml       ("..a" 는 정상 이름)
+//   base/escape-dir  -> outside/       (밖으로 나가는 디렉토리 링크)
+//   base/app/escape.yml -> outside/secret.yml
+//   base/inner-link -> base/app        (안쪽을 가리키는 링크는 허용)
+//   outside/secret.yml
+//   proj/docker-compose.yml            (등록 프로젝트 "@proj")
+func setupPathTree(t *testing.T) (base, outside string) {
+    root, err := filepath.EvalSymlinks(t.TempDir())
+    if err != nil {
+        t.Fatal(err)
+    }
+    base = filepath.Join(root, "base")
+    outside = filepath.Join(root, "outside")
+    proj := filepath.Join(root, "proj")
+    for _, d := range []string{filepath.Join(base, "app"), filepath.Join(base, "..a"), outside, proj} {
+        if err := os.MkdirAll(d, 0755); err != nil {
+            t.Fatal(err)
+        }
+    }
+    for _, f := range []string{
+        filepath.Join(base, "app", "docker-compose.yml"),
+        filepath.Join(base, "..a", "docker-compose.yml"),
+        filepath.Join(outside, "secret.yml"),
+        filepath.Join(proj, "docker-compose.yml"),
+    } {
+        if err := ioutil.WriteFile(f, []byte("services: {}\n"), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    links := map[string]string{
+        filepath.Join(base, "escape-dir"):        outside,
+        filepath.Join(base, "app", "escape.yml"): filepath.Join(outside, "secret.yml"),
+        filepath.Join(base, "inner-link"):        filepath.Join(base, "app"),
+    }
+    for link, target := range links {
+        if err := os.Symlink(target, link); err != nil {
+            t.Skipf("심볼릭 링크를 만들 수 없는 환경: %v", err)
+        }
+    }
+
+    oldBase, oldRegistry := baseDir, registry
+    baseDir = base
+    registry = projectRegistry{Projects: []*project{{Name: "proj", ComposeFile: filepath.Join(proj, "docker-compose.yml")}}}
+    t.Cleanup(func() { baseDir, registry = oldBase, oldRegistry })
+    return base, outside
+}
+
+func TestResolvePath(t *testing.T) {
+    base, _ := setupPathTree(t)
+    proj := filepath.Join(filepath.Dir(base), "proj")
+
+    tests := []struct {
+        name string
+        rel  string
+        want string // "" 이면 오류를 기대
+    }{
+        {"일반 파일", "app/docker-compose.yml", filepath.Join(base, "app", "docker-compose.yml")},
+        {"디렉토리", "app", filepath.Join(base, "app")},
+        {"아직 없는 파일", "app/new.yml", filepath.Join(base, "app", "new.yml")},
+        {"..a 는 정상 이름", "..a/docker-compose.yml", filepath.Join(base, "..a", "docker-compose.yml")},
+        {"안에서 끝나는 ..", "app/../app/docker-compose.yml", filepath.Join(base, "app", "docker-compose.yml")},
+        {"안쪽을 가리키는 링크", "inner-link/docker-compose.yml", filepath.Join(base, "app", "docker-compose.yml")},
+
+        {"빈 경로", "", ""},
+        {"상위로 이동", "../outside/secret.yml", ""},
+        {"중간에서 상위로 이동", "app/../../outside/secret.yml", ""},
+        {"상위 자체", "..", ""},
+        {"절대경로", "/etc/passwd", ""},
+        {"NUL 바이트", "app/docker-compose.yml\x00.txt", ""},
+        {"baseDir 자체", ".", ""},
+        {"baseDir 자체 (app/..)", "app/..", ""},
+        {"밖으로 나가는 디렉토리 링크", "escape-dir", ""},
+        {"밖으로 나가는 디렉토리 링크 안의 파일", "escape-dir/secret.yml", ""},
+        {"밖으로 나가는 링크 아래 아직 없는 파일", "escape-dir/new.yml", ""},
+        {"밖으로 나가는 링크 아래 아직 없는 디렉토리", "escape-dir/newdir/new.yml", ""},
+        {"밖을 가리키는 파일 링크", "app/escape.yml", ""},
+
+        {"등록 프로젝트 루트", "@proj", proj},
+        {"등록 프로젝트 파일", "@proj/docker-compose.yml", filepath.Join(proj, "docker-compose.yml")},
+        {"등록 프로젝트 상위", "@proj/..", ""},
+        {"등록 프로젝트 밖", "@proj/../base/app/docker-compose.yml", ""},
+        {"등록되지 않은 프로젝트", "@nope/docker-compose.yml", ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := resolvePath(tt.rel)
+            if tt.want == "" {
+                if err == nil {
+                    t.Fatalf("resolvePath(%q) = %q, 오류를 기대함", tt.rel, got)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("resolvePath(%q) 오류: %v", tt.rel, err)
+            }
+            if got != tt.want {
+                t.Fatalf("resolvePath(%q) = %q, want %q", tt.rel, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestResolveIn(t *testing.T) {
+    base, outside := setupPathTree(t)
+
+    tests := []struct {
+        name    string
+        root    string
+        rel     string
+        wantErr bool
+    }{
+        {"root 자체는 허용", base, ".", false},
+        {"root 하위", base, "app", false},
+        {"root 밖", base, "../outside", true},
+        {"링크로 root 밖", base, "escape-dir/secret.yml", true},
+        {"다른 root 에서는 정상", outside, "secret.yml", false},
+        {"절대경로", base, filepath.Join(base, "app"), true},
+        {"NUL 바이트", base, "app\x00", true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := resolveIn(tt.root, tt.rel)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("resolveIn(%q, %q) err = %v, wantErr %v", tt.root, tt.rel, err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestValidName(t *testing.T) {
+    tests := []struct {
+        name string
+        want bool
+    }{
+        {"docker-compose.yml", true},
+        {"..a", true},
+        {"a..", true},
+        {"", false},
+        {".", false},
+        {"..", false},
+        {"a/b", false},
+        {`a\b`, false},
+        {"a\x00b", false},
+    }
+    for _, tt := range tests {
+        if got := validName(tt.name); got != tt.want {
+            t.Errorf("validName(%q) = %v, want %v", tt.name, got, tt.want)
+        }
+    }
+}
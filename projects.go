@@ -0,0 +1,242 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ------------------------------------------------------
+// 20. 프로젝트 레지스트리 (baseDir 밖의 compose 파일 등록)
+// ------------------------------------------------------
+
+// 등록된 프로젝트. 웹에서는 "@이름" 디렉토리, "@이름/파일" 경로로 접근한다.
+type project struct {
+    Name        string    `json:"name"`
+    ComposeFile string    `json:"composeFile"` // compose 파일 절대경로
+    EnvFiles    []string  `json:"envFiles,omitempty"`
+    Owner       string    `json:"owner"`
+    CreatedAt   time.Time `json:"createdAt"`
+}
+
+// .projects 파일 형식
+type projectRegistry struct {
+    Projects []*project `json:"projects"`
+    // 프로젝트별 설정 (키: compose 파일이 있는 디렉토리, baseDir 하위 프로젝트 포함)
+    Settings map[string]*projectSettings `json:"settings,omitempty"`
+}
+
+// 프로젝트별 설정
+type projectSettings struct {
+    applyOptions
+}
+
+const projectFile = ".projects"
+
+// 웹 경로에서 등록 프로젝트를 나타내는 접두어
+const projectPrefix = "@"
+
+var projectNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+var (
+    registryMu sync.RWMutex
+    registry   projectRegistry
+)
+
+func loadProjects() error {
+    data, err := ioutil.ReadFile(projectFile)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil // .projects 파일이 없으면 그냥 반환
+        }
+        return err
+    }
+    registryMu.Lock()
+    defer registryMu.Unlock()
+    return json.Unmarshal(data, &registry)
+}
+
+// saveProjects: registryMu 를 잡은 상태에서 호출
+func saveProjects() error {
+    data, err := json.MarshalIndent(registry, "", "  ")
+    if err != nil {
+        return err
+    }
+    return ioutil.WriteFile(projectFile, data, 0644)
+}
+
+func findProject(name string) *project {
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+    for _, p := range registry.Projects {
+        if p.Name == name {
+            return p
+        }
+    }
+    return nil
+}
+
+func listProjects() []*project {
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+    result := append([]*project{}, registry.Projects...)
+    sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+    return result
+}
+
+// projectDir: 프로젝트 루트 (compose 파일이 있는 디렉토리)
+func (p *project) projectDir() string {
+    return filepath.Dir(p.ComposeFile)
+}
+
+// projectForFile: 파일이 속한 등록 프로젝트 (없으면 nil)
+func projectForFile(fullPath string) *project {
+    dir := filepath.Dir(fullPath)
+    for _, p := range listProjects() {
+        if real, err := filepath.EvalSymlinks(p.projectDir()); err == nil && real == dir {
+            return p
+        }
+        if p.projectDir() == dir {
+            return p
+        }
+    }
+    return nil
+}
+
+// isProjectComposeFile: 등록 프로젝트에 지정된 compose 파일인지 (이름이 docker-compose*.yml 형태가 아니어도)
+func isProjectComposeFile(fullPath string) bool {
+    p := projectForFile(fullPath)
+    return p != nil && filepath.Base(p.ComposeFile) == filepath.Base(fullPath)
+}
+
+// resolveProjectPath: "@이름" 또는 "@이름/상대경로" 를 프로젝트 루트 안의 실제 경로로 변환
+func resolveProjectPath(rel string) (string, error) {
+    rel = strings.TrimPrefix(rel, projectPrefix)
+    name, rest := rel, "."
+    if i := strings.IndexAny(rel, `/\`); i >= 0 {
+        name, rest = rel[:i], rel[i+1:]
+    }
+    p := findProject(name)
+    if p == nil {
+        return "", fmt.Errorf("등록되지 않은 프로젝트: %s", name)
+    }
+    if rest == "" {
+        rest = "."
+    }
+    return resolveIn(p.projectDir(), rest)
+}
+
+// validateProject: 이름/경로 형식과 파일 존재 여부 확인
+func validateProject(p *project) error {
+    if !projectNamePattern.MatchString(p.Name) {
+        return fmt.Errorf("프로젝트 이름은 영문/숫자/.-_ 만 사용할 수 있습니다.")
+    }
+    if !filepath.IsAbs(p.ComposeFile) {
+        return fmt.Errorf("compose 파일은 절대경로여야 합니다.")
+    }
+    p.ComposeFile = filepath.Clean(p.ComposeFile)
+    if info, err := os.Stat(p.ComposeFile); err != nil || info.IsDir() {
+        return fmt.Errorf("compose 파일을 찾을 수 없습니다: %s", p.ComposeFile)
+    }
+    for i, env := range p.EnvFiles {
+        if !filepath.IsAbs(env) {
+            return fmt.Errorf("env 파일은 절대경로여야 합니다: %s", env)
+        }
+        p.EnvFiles[i] = filepath.Clean(env)
+        if _, err := os.Stat(p.EnvFiles[i]); err != nil {
+            return fmt.Errorf("env 파일을 찾을 수 없습니다: %s", env)
+        }
+    }
+    return nil
+}
+
+// splitList: 줄바꿈/쉼표로 구분된 입력을 목록으로
+func splitList(s string) []string {
+    var result []string
+    for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == '\r' || r == ',' }) {
+        if f = strings.TrimSpace(f); f != "" {
+            result = append(result, f)
+        }
+    }
+    return result
+}
+
+// 프로젝트 목록 (JSON)
+func listProjectsAPI(c *gin.Context) {
+    c.JSON(http.StatusOK, listProjects())
+}
+
+// 프로젝트 등록/수정
+func saveProjectAPI(c *gin.Context) {
+    p := &project{
+        Name:        strings.TrimSpace(c.PostForm("name")),
+        ComposeFile: strings.TrimSpace(c.PostForm("composeFile")),
+        EnvFiles:    splitList(c.PostForm("envFiles")),
+        Owner:       strings.TrimSpace(c.PostForm("owner")),
+        CreatedAt:   time.Now(),
+    }
+    if p.Owner == "" {
+        p.Owner = currentUser(c).Email
+    }
+    ev := newAudit(c, "project", projectPrefix+p.Name)
+    ev.Detail = p.ComposeFile
+    if err := validateProject(p); err != nil {
+        ev.record(err)
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
+
+    registryMu.Lock()
+    defer registryMu.Unlock()
+    replaced := false
+    for i, old := range registry.Projects {
+        if old.Name == p.Name {
+            p.CreatedAt = old.CreatedAt
+            registry.Projects[i] = p
+            replaced = true
+            break
+        }
+    }
+    if !replaced {
+        registry.Projects = append(registry.Projects, p)
+    }
+    err := saveProjects()
+    ev.record(err)
+    if err != nil {
+        c.String(http.StatusInternalServerError, fmt.Sprintf("프로젝트 저장 오류: %v", err))
+        return
+    }
+    c.Redirect(http.StatusFound, "/console/admin")
+}
+
+// 프로젝트 삭제 (등록만 해제하며 실제 파일은 건드리지 않음)
+func deleteProjectAPI(c *gin.Context) {
+    name := c.PostForm("name")
+    ev := newAudit(c, "project-delete", projectPrefix+name)
+    registryMu.Lock()
+    defer registryMu.Unlock()
+    for i, p := range registry.Projects {
+        if p.Name == name {
+            registry.Projects = append(registry.Projects[:i], registry.Projects[i+1:]...)
+            err := saveProjects()
+            ev.record(err)
+            if err != nil {
+                c.String(http.StatusInternalServerError, fmt.Sprintf("프로젝트 저장 오류: %v", err))
+                return
+            }
+            c.Redirect(http.StatusFound, "/console/admin")
+            return
+        }
+    }
+    c.String(http.StatusBadRequest, "프로젝트를 찾을 수 없습니다.")
+}
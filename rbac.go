@@ -0,0 +1,259 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ------------------------------------------------------
+// 24. 역할 기반 권한 (viewer / operator / admin)
+// ------------------------------------------------------
+
+// 역할
+const (
+    roleNone     = "none"     // 가입만 한 상태 (콘솔 사용 불가)
+    roleViewer   = "viewer"   // 파일/상태/로그/백업 조회
+    roleOperator = "operator" // + 재시작, 롤백, 서비스 액션
+    roleAdmin    = "admin"    // + 파일 편집/생성, 사용자/프로젝트 관리
+)
+
+// 관리 화면에 표시하는 순서
+var roles = []string{roleNone, roleViewer, roleOperator, roleAdmin}
+
+// 권한
+const (
+    permRead    = "read"    // 디렉토리/파일/백업/diff/상태/로그/작업 조회
+    permOperate = "operate" // 재시작, 롤백, 서비스 액션
+    permEdit    = "edit"    // 파일 저장, 파일/디렉토리 생성, 프로젝트 적용 설정
+    permAdmin   = "admin"   // 사용자 권한, 프로젝트 레지스트리 관리
+)
+
+// 역할별 권한
+var rolePermissions = map[string]map[string]bool{
+    roleNone:     {},
+    roleViewer:   {permRead: true},
+    roleOperator: {permRead: true, permOperate: true},
+    roleAdmin:    {permRead: true, permOperate: true, permEdit: true, permAdmin: true},
+}
+
+func validRole(role string) bool {
+    _, ok := rolePermissions[role]
+    return ok
+}
+
+// roleRank: 역할의 높낮이 (roles 순서)
+func roleRank(role string) int {
+    for i, r := range roles {
+        if r == role {
+            return i
+        }
+    }
+    return 0
+}
+
+// hasPermission: 사용자의 전역 역할에 perm 이 포함되는지 확인
+func hasPermission(u *User, perm string) bool {
+    return u != nil && rolePermissions[u.Role][perm]
+}
+
+// roleFor: 프로젝트에 대한 실제 역할 (전역 역할과 프로젝트 권한 중 높은 것)
+func (u *User) roleFor(project string) string {
+    role := u.Role
+    if g, ok := u.Grants[project]; ok && roleRank(g) > roleRank(role) {
+        role = g
+    }
+    return role
+}
+
+// hasProjectPermission: project 에 대해 perm 이 있는지 확인
+// admin 권한(사용자/레지스트리 관리)은 프로젝트 권한으로 줄 수 없고 전역 역할로만 판단한다.
+func hasProjectPermission(u *User, project, perm string) bool {
+    if u == nil {
+        return false
+    }
+    if perm == permAdmin {
+        return hasPermission(u, perm)
+    }
+    return rolePermissions[u.roleFor(project)][perm]
+}
+
+// hasAnyPermission: 전역 역할 또는 어느 한 프로젝트에서라도 perm 이 있는지 확인
+func hasAnyPermission(u *User, perm string) bool {
+    if hasPermission(u, perm) {
+        return true
+    }
+    if u == nil || perm == permAdmin {
+        return false
+    }
+    for project := range u.Grants {
+        if hasProjectPermission(u, project, perm) {
+            return true
+        }
+    }
+    return false
+}
+
+// projectOf: 실제 경로(파일/디렉토리)가 속한 프로젝트 이름
+// baseDir 하위는 첫 번째 디렉토리명(예: "payments"), 등록 프로젝트는 "@이름"
+func projectOf(fullPath string) string {
+    for _, p := range listProjects() {
+        root, err := filepath.EvalSymlinks(p.projectDir())
+        if err != nil {
+            root = p.projectDir()
+        }
+        if within(root, fullPath) {
+            return projectPrefix + p.Name
+        }
+    }
+    root, err := resolveIn(baseDir, ".")
+    if err != nil {
+        return ""
+    }
+    rel, err := filepath.Rel(root, fullPath)
+    if err != nil || rel == "." || !within(root, fullPath) {
+        return ""
+    }
+    return strings.Split(rel, string(filepath.Separator))[0]
+}
+
+// 요청에서 대상 경로로 쓰이는 파라미터 (쿼리 또는 폼)
+var targetParams = []string{"path", "target", "dir", "dirname"}
+
+// requirePermission: perm 권한이 없으면 403 으로 중단하는 미들웨어 (AuthRequired 뒤에 사용)
+// 요청에 대상 경로가 있으면 그 경로가 속한 프로젝트에 대한 권한을 확인한다.
+func requirePermission(perm string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        u := currentUser(c)
+        if !tokenAllows(c, perm) {
+            c.String(http.StatusForbidden, "API 토큰의 권한 범위에 "+perm+" 가 없습니다.")
+            c.Abort()
+            return
+        }
+        if !hasAnyPermission(u, perm) {
+            msg := "이 기능을 사용할 권한이 없습니다. (필요 권한: " + perm + ")\n" +
+                "어드민 이메일: " + firstRegisteredUserEmail
+            c.String(http.StatusForbidden, msg)
+            c.Abort()
+            return
+        }
+        for _, key := range targetParams {
+            for _, rel := range []string{c.Query(key), c.PostForm(key)} {
+                if rel == "" {
+                    continue
+                }
+                // 잘못된 경로는 핸들러에서 400 으로 처리
+                fullPath, err := resolvePath(rel)
+                if err != nil {
+                    continue
+                }
+                if project := projectOf(fullPath); !hasProjectPermission(u, project, perm) {
+                    c.String(http.StatusForbidden, fmt.Sprintf("%s 프로젝트에 대한 권한이 없습니다. (필요 권한: %s)", project, perm))
+                    c.Abort()
+                    return
+                }
+            }
+        }
+        c.Next()
+    }
+}
+
+// canAccess: 핸들러 안에서 실제 경로에 대한 권한 확인 (목록 필터링, 작업 조회 등)
+func canAccess(c *gin.Context, fullPath, perm string) bool {
+    return tokenAllows(c, perm) && hasProjectPermission(currentUser(c), projectOf(fullPath), perm)
+}
+
+// ------------------------------------------------------
+// 24-1. 프로젝트별 권한 (예: payments=operator, shared=viewer)
+// ------------------------------------------------------
+
+// parseGrants: "프로젝트=역할" 목록 (쉼표/세미콜론/줄바꿈 구분)
+func parseGrants(s string) (map[string]string, error) {
+    grants := make(map[string]string)
+    for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == ',' || r == '\n' || r == '\r' }) {
+        item = strings.TrimSpace(item)
+        if item == "" {
+            continue
+        }
+        kv := strings.SplitN(item, "=", 2)
+        if len(kv) != 2 {
+            return nil, fmt.Errorf("형식 오류 (프로젝트=역할): %s", item)
+        }
+        project, role := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+        if !validName(strings.TrimPrefix(project, projectPrefix)) || strings.ContainsAny(project, "=;,") {
+            return nil, fmt.Errorf("잘못된 프로젝트 이름: %s", project)
+        }
+        // 프로젝트의 admin 은 해당 프로젝트 파일 편집까지이며 사용자/레지스트리 관리는 포함하지 않음
+        if !validRole(role) || role == roleNone {
+            return nil, fmt.Errorf("프로젝트 권한은 viewer, operator, admin 중 하나여야 합니다: %s", item)
+        }
+        grants[project] = role
+    }
+    return grants, nil
+}
+
+// formatGrants: .account 및 화면 표시용 ("a=operator;b=viewer", 이름순)
+func formatGrants(grants map[string]string) string {
+    var items []string
+    for project, role := range grants {
+        items = append(items, project+"="+role)
+    }
+    sort.Strings(items)
+    return strings.Join(items, ";")
+}
+
+// 사용자 프로젝트 권한 변경 (어드민)
+func updateUserGrants(c *gin.Context) {
+    email := c.PostForm("email")
+    u, ok := users[email]
+    if !ok {
+        c.String(http.StatusBadRequest, "사용자를 찾을 수 없습니다.")
+        return
+    }
+    ev := newAudit(c, "grants", email)
+    grants, err := parseGrants(c.PostForm("grants"))
+    if err != nil {
+        ev.record(err)
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
+    ev.Detail = formatGrants(u.Grants) + " -> " + formatGrants(grants)
+    u.Grants = grants
+    err = saveAccounts()
+    ev.record(err)
+    if err != nil {
+        c.String(http.StatusInternalServerError, fmt.Sprintf("계정 저장 오류: %v", err))
+        return
+    }
+    c.String(http.StatusOK, "프로젝트 권한이 업데이트되었습니다. <a href='/console/admin'>돌아가기</a>")
+}
+
+// 현재 사용자의 경로별 권한 (콘솔 버튼 표시용)
+func permissionsAPI(c *gin.Context) {
+    fullPath, err := resolvePath(c.Query("path"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "project": projectOf(fullPath),
+        "read":    canAccess(c, fullPath, permRead),
+        "operate": canAccess(c, fullPath, permOperate),
+        "edit":    canAccess(c, fullPath, permEdit),
+    })
+}
+
+// adminCount: admin 역할 사용자 수
+func adminCount() int {
+    n := 0
+    for _, u := range users {
+        if u.Role == roleAdmin {
+            n++
+        }
+    }
+    return n
+}
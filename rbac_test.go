@@ -0,0 +1,300 @@
+package main
+
+import (
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+
+    "github.com/gin-gonic/gin"
+)
+
+func TestParseGrants(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    map[string]string
+        wantErr bool
+    }{
+        {"", map[string]string{}, false},
+        {"payments=operator", map[string]string{"payments": "operator"}, false},
+        {" payments = operator ; shared=viewer ", map[string]string{"payments": "operator", "shared": "viewer"}, false},
+        {"payments=operator,shared=viewer\r\n@ext=admin\n", map[string]string{"payments": "operator", "shared": "viewer", "@ext": "admin"}, false},
+        {"payments=viewer;payments=admin", map[string]string{"payments": "admin"}, false},
+
+        {"payments", nil, true},
+        {"payments=", nil, true},
+        {"payments=none", nil, true},
+        {"payments=root", nil, true},
+        {"=viewer", nil, true},
+        {"..=viewer", nil, true},
+        {"a/b=viewer", nil, true},
+        {"@=viewer", nil, true},
+        {"a=b=viewer", nil, true},
+    }
+    for _, tt := range tests {
+        got, err := parseGrants(tt.in)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("parseGrants(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+            continue
+        }
+        if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("parseGrants(%q) = %v, want %v", tt.in, got, tt.want)
+        }
+    }
+
+    // formatGrants 결과는 다시 읽어도 같아야 한다
+    grants := map[string]string{"shared": "viewer", "payments": "operator", "@ext": "admin"}
+    s := formatGrants(grants)
+    if s != "@ext=admin;payments=operator;shared=viewer" {
+        t.Errorf("formatGrants = %q", s)
+    }
+    if back, err := parseGrants(s); err != nil || !reflect.DeepEqual(back, grants) {
+        t.Errorf("parseGrants(formatGrants) = %v, %v", back, err)
+    }
+}
+
+// 권한 테스트용 사용자
+var (
+    rbacNone     = &User{Email: "none@test", Role: roleNone}
+    rbacViewer   = &User{Email: "viewer@test", Role: roleViewer}
+    rbacOperator = &User{Email: "operator@test", Role: roleOperator}
+    rbacAdmin    = &User{Email: "admin@test", Role: roleAdmin}
+    // 전역 역할은 없고 payments 만 운영, shared 는 조회
+    rbacTeam = &User{Email: "team@test", Role: roleNone, Grants: map[string]string{"payments": roleOperator, "shared": roleViewer}}
+    // 전역 viewer 이지만 등록 프로젝트 @ext 는 프로젝트 admin
+    rbacExtAdmin = &User{Email: "ext@test", Role: roleViewer, Grants: map[string]string{"@ext": roleAdmin}}
+    // 프로젝트 권한이 전역 역할보다 낮으면 전역 역할을 따른다
+    rbacLowGrant = &User{Email: "low@test", Role: roleOperator, Grants: map[string]string{"payments": roleViewer}}
+)
+
+func TestHasProjectPermission(t *testing.T) {
+    tests := []struct {
+        user    *User
+        project string
+        perm    string
+        want    bool
+    }{
+        {nil, "payments", permRead, false},
+
+        {rbacNone, "payments", permRead, false},
+        {rbacViewer, "payments", permRead, true},
+        {rbacViewer, "payments", permOperate, false},
+        {rbacViewer, "payments", permEdit, false},
+        {rbacOperator, "payments", permOperate, true},
+        {rbacOperator, "payments", permEdit, false},
+        {rbacAdmin, "payments", permEdit, true},
+        {rbacAdmin, "", permAdmin, true},
+
+        {rbacTeam, "payments", permRead, true},
+        {rbacTeam, "payments", permOperate, true},
+        {rbacTeam, "payments", permEdit, false},
+        {rbacTeam, "shared", permRead, true},
+        {rbacTeam, "shared", permOperate, false},
+        {rbacTeam, "other", permRead, false},
+        {rbacTeam, "", permRead, false},
+
+        {rbacExtAdmin, "@ext", permEdit, true},
+        {rbacExtAdmin, "payments", permRead, true},
+        {rbacExtAdmin, "payments", permEdit, false},
+        // 프로젝트 admin 으로 사용자/레지스트리 관리 권한을 얻을 수는 없다
+        {rbacExtAdmin, "@ext", permAdmin, false},
+
+        {rbacLowGrant, "payments", permOperate, true},
+    }
+    for _, tt := range tests {
+        email := "<nil>"
+        if tt.user != nil {
+            email = tt.user.Email
+        }
+        if got := hasProjectPermission(tt.user, tt.project, tt.perm); got != tt.want {
+            t.Errorf("hasProjectPermission(%s, %q, %s) = %v, want %v", email, tt.project, tt.perm, got, tt.want)
+        }
+    }
+}
+
+func TestHasAnyPermission(t *testing.T) {
+    tests := []struct {
+        user *User
+        perm string
+        want bool
+    }{
+        {nil, permRead, false},
+        {rbacNone, permRead, false},
+        {rbacTeam, permOperate, true},
+        {rbacTeam, permEdit, false},
+        {rbacExtAdmin, permEdit, true},
+        {rbacExtAdmin, permAdmin, false},
+        {rbacAdmin, permAdmin, true},
+    }
+    for _, tt := range tests {
+        email := "<nil>"
+        if tt.user != nil {
+            email = tt.user.Email
+        }
+        if got := hasAnyPermission(tt.user, tt.perm); got != tt.want {
+            t.Errorf("hasAnyPermission(%s, %s) = %v, want %v", email, tt.perm, got, tt.want)
+        }
+    }
+}
+
+// setupRBACTree: base/payments, base/shared, base/other 와 등록 프로젝트 @ext
+func setupRBACTree(t *testing.T) {
+    root, err := filepath.EvalSymlinks(t.TempDir())
+    if err != nil {
+        t.Fatal(err)
+    }
+    base := filepath.Join(root, "base")
+    ext := filepath.Join(root, "ext")
+    for _, d := range []string{"payments", "shared", "other"} {
+        if err := os.MkdirAll(filepath.Join(base, d), 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := ioutil.WriteFile(filepath.Join(base, d, "docker-compose.yml"), []byte("services: {}\n"), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := os.MkdirAll(ext, 0755); err != nil {
+        t.Fatal(err)
+    }
+    if err := ioutil.WriteFile(filepath.Join(ext, "stack.yml"), []byte("services: {}\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    oldBase, oldRegistry := baseDir, registry
+    baseDir = base
+    registry = projectRegistry{Projects: []*project{{Name: "ext", ComposeFile: filepath.Join(ext, "stack.yml")}}}
+    t.Cleanup(func() { baseDir, registry = oldBase, oldRegistry })
+}
+
+func TestProjectOf(t *testing.T) {
+    setupRBACTree(t)
+    for rel, want := range map[string]string{
+        "payments":                    "payments",
+        "payments/docker-compose.yml": "payments",
+        "shared/docker-compose.yml":   "shared",
+        "@ext":                        "@ext",
+        "@ext/stack.yml":              "@ext",
+    } {
+        fullPath, err := resolvePath(rel)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got := projectOf(fullPath); got != want {
+            t.Errorf("projectOf(%s) = %q, want %q", rel, got, want)
+        }
+    }
+}
+
+func TestRequirePermission(t *testing.T) {
+    setupRBACTree(t)
+    setTestUsers(t, rbacNone, rbacViewer, rbacOperator, rbacAdmin, rbacTeam, rbacExtAdmin)
+
+    r := testRouter()
+    ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+    for _, perm := range []string{permRead, permOperate, permEdit, permAdmin} {
+        r.GET("/"+perm, requirePermission(perm), ok)
+        r.POST("/"+perm, requirePermission(perm), ok)
+    }
+
+    tests := []struct {
+        name  string
+        user  *User
+        perm  string
+        key   string // 대상 경로 파라미터 ("" 이면 없음)
+        value string
+        form  bool // 쿼리 대신 폼으로 보냄
+        want  int
+    }{
+        {"viewer 조회", rbacViewer, permRead, "path", "payments/docker-compose.yml", false, http.StatusOK},
+        {"viewer 재시작 거부", rbacViewer, permOperate, "path", "payments/docker-compose.yml", true, http.StatusForbidden},
+        {"viewer 편집 거부", rbacViewer, permEdit, "", "", true, http.StatusForbidden},
+        {"operator 롤백 (target)", rbacOperator, permOperate, "target", "shared/docker-compose.yml", true, http.StatusOK},
+        {"operator 편집 거부", rbacOperator, permEdit, "path", "shared/docker-compose.yml", true, http.StatusForbidden},
+        {"admin 편집 (dirname)", rbacAdmin, permEdit, "dirname", "newdir", true, http.StatusOK},
+        {"admin 사용자 관리", rbacAdmin, permAdmin, "", "", true, http.StatusOK},
+        {"권한 없는 사용자", rbacNone, permRead, "", "", false, http.StatusForbidden},
+        {"로그인하지 않음", nil, permRead, "", "", false, http.StatusForbidden},
+
+        {"프로젝트 운영 권한 (path)", rbacTeam, permOperate, "path", "payments/docker-compose.yml", true, http.StatusOK},
+        {"프로젝트 운영 권한 (dir)", rbacTeam, permRead, "dir", "payments", false, http.StatusOK},
+        {"A 프로젝트 권한으로 B 재시작 거부", rbacTeam, permOperate, "path", "other/docker-compose.yml", true, http.StatusForbidden},
+        {"A 프로젝트 권한으로 B 조회 거부 (dir)", rbacTeam, permRead, "dir", "other", false, http.StatusForbidden},
+        {"A 프로젝트 권한으로 B 롤백 거부 (target)", rbacTeam, permOperate, "target", "other/docker-compose.yml", true, http.StatusForbidden},
+        {"조회만 가능한 프로젝트 재시작 거부", rbacTeam, permOperate, "path", "shared/docker-compose.yml", true, http.StatusForbidden},
+        {"조회 권한으로 디렉토리 생성 거부 (dirname)", rbacTeam, permEdit, "dirname", "shared", true, http.StatusForbidden},
+        {"프로젝트 권한으로 사용자 관리 거부", rbacTeam, permAdmin, "", "", true, http.StatusForbidden},
+
+        {"등록 프로젝트 편집 (@project)", rbacExtAdmin, permEdit, "path", "@ext/stack.yml", true, http.StatusOK},
+        {"등록 프로젝트 admin 으로 다른 프로젝트 편집 거부", rbacExtAdmin, permEdit, "path", "payments/docker-compose.yml", true, http.StatusForbidden},
+        {"등록 프로젝트 권한 없이 @project 재시작 거부", rbacTeam, permOperate, "path", "@ext/stack.yml", true, http.StatusForbidden},
+        {"전역 operator 는 @project 재시작", rbacOperator, permOperate, "path", "@ext/stack.yml", true, http.StatusOK},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            header := map[string]string{}
+            if tt.user != nil {
+                header[testUserHeader] = tt.user.Email
+            }
+            var w *httptest.ResponseRecorder
+            switch {
+            case tt.form:
+                form := url.Values{}
+                if tt.key != "" {
+                    form.Set(tt.key, tt.value)
+                }
+                w = testRequest(r, http.MethodPost, "/"+tt.perm, form, header)
+            case tt.key != "":
+                w = testRequest(r, http.MethodGet, "/"+tt.perm+"?"+url.Values{tt.key: {tt.value}}.Encode(), nil, header)
+            default:
+                w = testRequest(r, http.MethodGet, "/"+tt.perm, nil, header)
+            }
+            if w.Code != tt.want {
+                t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+            }
+        })
+    }
+}
+
+func TestRequirePermissionChecksEveryTarget(t *testing.T) {
+    // 쿼리와 폼에 서로 다른 경로를 넣어 권한 있는 쪽만 보이게 하는 우회를 막는다
+    setupRBACTree(t)
+    setTestUsers(t, rbacTeam)
+    r := testRouter()
+    r.POST("/operate", requirePermission(permOperate), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+    header := map[string]string{testUserHeader: rbacTeam.Email}
+    w := testRequest(r, http.MethodPost, "/operate?path=payments/docker-compose.yml",
+        url.Values{"target": {"other/docker-compose.yml"}}, header)
+    if w.Code != http.StatusForbidden {
+        t.Fatalf("쿼리와 폼 중 하나라도 권한이 없으면 거부해야 함: %d", w.Code)
+    }
+    w = testRequest(r, http.MethodPost, "/operate?path=payments/docker-compose.yml",
+        url.Values{"path": {"other/docker-compose.yml"}}, header)
+    if w.Code != http.StatusForbidden {
+        t.Fatalf("같은 키의 쿼리/폼 값도 모두 확인해야 함: %d", w.Code)
+    }
+}
+
+func TestRequirePermissionTokenScope(t *testing.T) {
+    setupRBACTree(t)
+    setTestUsers(t, rbacAdmin)
+    r := testRouter()
+    r.Use(func(c *gin.Context) {
+        c.Set(ctxAPIToken, &apiToken{Owner: rbacAdmin.Email, Scopes: []string{permRead, permOperate}})
+    })
+    ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+    r.POST("/operate", requirePermission(permOperate), ok)
+    r.POST("/edit", requirePermission(permEdit), ok)
+
+    form := url.Values{"path": {"payments/docker-compose.yml"}}
+    if w := testRequest(r, http.MethodPost, "/operate", form, nil); w.Code != http.StatusOK {
+        t.Fatalf("토큰 범위 안의 권한: %d %s", w.Code, w.Body.String())
+    }
+    // 소유자가 admin 이어도 토큰 범위 밖이면 거부
+    if w := testRequest(r, http.MethodPost, "/edit", form, nil); w.Code != http.StatusForbidden {
+        t.Fatalf("토큰 범위 밖의 권한: %d", w.Code)
+    }
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// ------------------------------------------------------
+// 18. 리비전 저장소 (backups/ 파일 복사 또는 git)
+// ------------------------------------------------------
+
+// 리비전 한 건. 파일 백엔드는 백업 파일명, git 백엔드는 커밋 해시가 ID 이다.
+// 메타데이터(작성자/메시지/해시 등)는 두 백엔드 모두 "그 저장/롤백 작업"을 설명한다.
+// 내용은 git 커밋이 작업 후의 내용, 파일 백엔드의 백업은 작업 직전의 내용이다 (Before).
+type revision struct {
+    ID     string    `json:"id"`
+    Name   string    `json:"name"`
+    Time   time.Time `json:"time"`
+    Before bool      `json:"before"` // true 면 이 리비전의 내용은 메타데이터가 설명하는 작업 직전의 사본
+    revisionMeta
+}
+
+// 저장/롤백 시 함께 기록할 정보
+type revisionMeta struct {
+    Author        string `json:"author,omitempty"`        // 세션 사용자 이메일
+    Message       string `json:"message,omitempty"`       // 사용자가 입력한 변경 메시지
+    Action        string `json:"action,omitempty"`        // "save" 또는 "rollback"
+    Restart       bool   `json:"restart"`                 // 재시작 요청 여부
+    RestartResult string `json:"restartResult,omitempty"` // 재시작 결과 ("성공" 또는 오류 내용)
+    Hash          string `json:"hash,omitempty"`          // 이 작업으로 저장된 내용의 sha256
+}
+
+// contentHash: 내용의 sha256 (hex)
+func contentHash(data []byte) string {
+    sum := sha256.Sum256(data)
+    return hex.EncodeToString(sum[:])
+}
+
+type revisionStore interface {
+    // Commit: fullPath 를 data 로 갱신하고 변경 이력을 남긴다.
+    // 생성된 리비전 ID 반환 (변경이 없어 리비전이 생기지 않으면 "")
+    Commit(fullPath string, data []byte, meta revisionMeta) (string, error)
+    // SetRestartResult: Commit 으로 만든 리비전에 재시작 결과 기록
+    SetRestartResult(fullPath, id, result string) error
+    // List: fullPath 의 리비전 목록 (최신순)
+    List(fullPath string) ([]revision, error)
+    // Read: 특정 리비전의 파일 내용
+    Read(fullPath, id string) ([]byte, error)
+}
+
+// 현재 사용 중인 리비전 저장소 (runServer 에서 .env 의 revision_backend 로 결정)
+var revisions revisionStore = fileRevisionStore{}
+
+// newRevisionStore: "file"(기본) 또는 "git"
+func newRevisionStore(backend string) (revisionStore, error) {
+    switch backend {
+    case "", "file":
+        return fileRevisionStore{}, nil
+    case "git":
+        // resolvePath 가 돌려주는 실제 경로와 비교할 수 있도록 심볼릭 링크까지 해석
+        root, err := resolveIn(baseDir, ".")
+        if err != nil {
+            return nil, err
+        }
+        store := gitRevisionStore{root: root}
+        if err := store.init(); err != nil {
+            return nil, err
+        }
+        return store, nil
+    default:
+        return nil, fmt.Errorf("알 수 없는 revision_backend: %s", backend)
+    }
+}
+
+// ------------------------------------------------------
+// 18-1. 파일 백엔드: 같은 디렉토리의 backups/ 에 저장 직전 내용의 타임스탬프 사본
+//       (메타데이터는 backups/.meta/<백업파일명>.json)
+// ------------------------------------------------------
+
+type fileRevisionStore struct{}
+
+func metaPath(fullPath, backupName string) string {
+    return filepath.Join(filepath.Dir(fullPath), "backups", ".meta", backupName+".json")
+}
+
+func writeMeta(fullPath, backupName string, meta revisionMeta) error {
+    p := metaPath(fullPath, backupName)
+    if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+        return err
+    }
+    data, err := json.MarshalIndent(meta, "", "  ")
+    if err != nil {
+        return err
+    }
+    return ioutil.WriteFile(p, data, 0644)
+}
+
+func readMeta(fullPath, backupName string) (revisionMeta, error) {
+    var meta revisionMeta
+    data, err := ioutil.ReadFile(metaPath(fullPath, backupName))
+    if err != nil {
+        return meta, err
+    }
+    err = json.Unmarshal(data, &meta)
+    return meta, err
+}
+
+// Commit: 덮어쓰기 전의 현재 내용을 backups/ 에 사본으로 남긴 뒤 저장한다. (저장 한 번에 사본 하나)
+// 사본의 메타데이터는 이 저장 작업(작성자/메시지/저장한 내용의 해시)을 설명하므로,
+// 사본으로 롤백하면 이 작업을 되돌리게 된다. 메타데이터가 없는 예전 백업도 같은 의미이다.
+func (fileRevisionStore) Commit(fullPath string, data []byte, meta revisionMeta) (string, error) {
+    // 저장 전 백업
+    name, err := backupFile(fullPath)
+    if err != nil {
+        return "", fmt.Errorf("백업 실패: %v", err)
+    }
+    if err := ioutil.WriteFile(fullPath, data, 0644); err != nil {
+        return "", fmt.Errorf("저장 실패: %v", err)
+    }
+    meta.Hash = contentHash(data)
+    if err := writeMeta(fullPath, name, meta); err != nil {
+        return name, fmt.Errorf("메타데이터 저장 실패: %v", err)
+    }
+    return name, nil
+}
+
+func (fileRevisionStore) SetRestartResult(fullPath, id, result string) error {
+    meta, err := readMeta(fullPath, id)
+    if err != nil {
+        return err
+    }
+    meta.RestartResult = result
+    return writeMeta(fullPath, id, meta)
+}
+
+func (fileRevisionStore) List(fullPath string) ([]revision, error) {
+    // 파일명에서 base / ext 추출
+    fileName := filepath.Base(fullPath)
+    ext := filepath.Ext(fileName)
+    base := fileName[0 : len(fileName)-len(ext)]
+
+    // 해당 파일 디렉토리의 backups 폴더 (없으면 목록 없음)
+    localBackupDir := filepath.Join(filepath.Dir(fullPath), "backups")
+    files, err := ioutil.ReadDir(localBackupDir)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, nil
+        }
+        return nil, err
+    }
+
+    var result []revision
+    for _, f := range files {
+        if !f.IsDir() && strings.HasPrefix(f.Name(), base+"_") && strings.HasSuffix(f.Name(), ext) {
+            rev := revision{ID: f.Name(), Name: f.Name(), Time: f.ModTime(), Before: true}
+            // 메타데이터가 없는 예전 백업은 이름/시간만 표시
+            if meta, err := readMeta(fullPath, f.Name()); err == nil {
+                rev.revisionMeta = meta
+            }
+            result = append(result, rev)
+        }
+    }
+    // 같은 시각이면 이름순 (같은 초의 사본은 _2, _3 … 이 붙음)
+    sort.Slice(result, func(i, j int) bool {
+        if !result[i].Time.Equal(result[j].Time) {
+            return result[i].Time.After(result[j].Time)
+        }
+        return result[i].Name > result[j].Name
+    })
+    return result, nil
+}
+
+func (fileRevisionStore) Read(fullPath, id string) ([]byte, error) {
+    // 백업 파일명은 backups/ 안의 단일 이름만 허용 ("../" 등 차단)
+    if !validName(id) {
+        return nil, errPathOutside
+    }
+    return ioutil.ReadFile(filepath.Join(filepath.Dir(fullPath), "backups", id))
+}
+
+// ------------------------------------------------------
+// 18-2. git 백엔드: baseDir 를 git 저장소로 사용하여 저장/롤백마다 커밋
+// ------------------------------------------------------
+
+type gitRevisionStore struct {
+    root string // git 저장소 루트 (절대경로)
+}
+
+// 모든 프로젝트가 하나의 저장소/인덱스를 쓰므로 (작업 잠금은 프로젝트 단위)
+// add+commit, notes, show 는 저장소 전체에서 한 번에 하나씩 실행한다. (.git/index.lock 충돌 방지)
+var gitMu sync.Mutex
+
+// 웹콘솔이 직접 만드는 커밋의 committer 정보
+const gitCommitterName = "dc_webconsole"
+const gitCommitterEmail = "dc_webconsole@localhost"
+
+var gitRevPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+func (g gitRevisionStore) git(args ...string) ([]byte, error) {
+    cmd := exec.Command("git", args...)
+    cmd.Dir = g.root
+    // 커밋 작성자는 --author 로 지정하고, 그 외(notes 등)는 웹콘솔 계정으로 기록
+    cmd.Env = append(os.Environ(),
+        "GIT_AUTHOR_NAME="+gitCommitterName,
+        "GIT_AUTHOR_EMAIL="+gitCommitterEmail,
+        "GIT_COMMITTER_NAME="+gitCommitterName,
+        "GIT_COMMITTER_EMAIL="+gitCommitterEmail,
+    )
+    var stderr bytes.Buffer
+    cmd.Stderr = &stderr
+    out, err := cmd.Output()
+    if err != nil {
+        return out, fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+    }
+    return out, nil
+}
+
+// init: 저장소가 없으면 git init
+func (g gitRevisionStore) init() error {
+    if _, err := os.Stat(filepath.Join(g.root, ".git")); err == nil {
+        return nil
+    }
+    if _, err := g.git("init", "-q"); err != nil {
+        return err
+    }
+    // 기존 backups/ 사본은 이력 대상에서 제외
+    return ioutil.WriteFile(filepath.Join(g.root, ".gitignore"), []byte("backups/\n.validate-*\n"), 0644)
+}
+
+// relPath: 저장소 루트 기준 상대경로
+func (g gitRevisionStore) relPath(fullPath string) (string, error) {
+    abs, err := filepath.Abs(fullPath)
+    if err != nil {
+        return "", err
+    }
+    rel, err := filepath.Rel(g.root, abs)
+    if err != nil || strings.HasPrefix(rel, "..") {
+        return "", fmt.Errorf("git 저장소 밖의 파일입니다: %s", fullPath)
+    }
+    return filepath.ToSlash(rel), nil
+}
+
+// commitPath: rel 경로에 변경이 있으면 커밋하고 true 반환 (없으면 아무것도 하지 않음)
+func (g gitRevisionStore) commitPath(rel, author, message string) (bool, error) {
+    if _, err := g.git("add", "--", rel); err != nil {
+        return false, err
+    }
+    if _, err := g.git("diff", "--cached", "--quiet", "--", rel); err == nil {
+        return false, nil // 변경 없음
+    }
+    if author == "" {
+        author = gitCommitterEmail
+    }
+    _, err := g.git("commit", "-q", "-m", message, "--author", fmt.Sprintf("%s <%s>", author, author), "--", rel)
+    return err == nil, err
+}
+
+// 커밋 메시지 본문에 남기는 trailer 키
+const (
+    trailerAction  = "Action"
+    trailerRestart = "Restart"
+    trailerHash    = "Content-Sha256"
+)
+
+// 저장소 밖의 파일(등록 프로젝트 등)은 파일 백엔드로 이력을 남긴다
+func (g gitRevisionStore) Commit(fullPath string, data []byte, meta revisionMeta) (string, error) {
+    rel, err := g.relPath(fullPath)
+    if err != nil {
+        return fileRevisionStore{}.Commit(fullPath, data, meta)
+    }
+    gitMu.Lock()
+    defer gitMu.Unlock()
+    // 웹콘솔 밖에서 바뀐 내용(또는 최초 파일)이 있으면 먼저 커밋해 둔다
+    if _, err := os.Stat(fullPath); err == nil {
+        if _, err := g.commitPath(rel, "", "웹콘솔 외부 변경 사항 가져오기: "+rel); err != nil {
+            return "", err
+        }
+    }
+
+    if err := ioutil.WriteFile(fullPath, data, 0644); err != nil {
+        return "", fmt.Errorf("저장 실패: %v", err)
+    }
+    message := strings.TrimSpace(meta.Message)
+    if message == "" {
+        message = fmt.Sprintf("%s: %s", meta.Action, rel)
+    }
+    message += fmt.Sprintf("\n\n%s: %s\n%s: %t\n%s: %s\n",
+        trailerAction, meta.Action, trailerRestart, meta.Restart, trailerHash, contentHash(data))
+    committed, err := g.commitPath(rel, meta.Author, message)
+    if err != nil || !committed {
+        return "", err
+    }
+    out, err := g.git("rev-parse", "HEAD")
+    if err != nil {
+        return "", err
+    }
+    return strings.TrimSpace(string(out)), nil
+}
+
+func (g gitRevisionStore) List(fullPath string) ([]revision, error) {
+    rel, err := g.relPath(fullPath)
+    if err != nil {
+        return fileRevisionStore{}.List(fullPath)
+    }
+    // 레코드 구분: %x1e, 필드 구분: %x1f (본문/노트는 여러 줄일 수 있음)
+    out, err := g.git("log", "--format=%H%x1f%ae%x1f%at%x1f%s%x1f%b%x1f%N%x1e", "--", rel)
+    if err != nil {
+        // 아직 커밋이 하나도 없는 저장소
+        if _, headErr := g.git("rev-parse", "--verify", "-q", "HEAD"); headErr != nil {
+            return nil, nil
+        }
+        return nil, err
+    }
+    var result []revision
+    for _, record := range strings.Split(string(out), "\x1e") {
+        fields := strings.Split(strings.TrimSpace(record), "\x1f")
+        if len(fields) < 6 {
+            continue
+        }
+        ts, _ := strconv.ParseInt(fields[2], 10, 64)
+        rev := revision{
+            ID:   fields[0],
+            Name: fields[0][:8],
+            Time: time.Unix(ts, 0),
+        }
+        rev.Author = fields[1]
+        rev.Message = fields[3]
+        rev.RestartResult = strings.TrimSpace(fields[5])
+        for _, line := range strings.Split(fields[4], "\n") {
+            kv := strings.SplitN(line, ": ", 2)
+            if len(kv) != 2 {
+                continue
+            }
+            switch kv[0] {
+            case trailerAction:
+                rev.Action = kv[1]
+            case trailerRestart:
+                rev.Restart = kv[1] == "true"
+            case trailerHash:
+                rev.Hash = kv[1]
+            }
+        }
+        result = append(result, rev)
+    }
+    return result, nil
+}
+
+// SetRestartResult: 재시작 결과는 커밋 이후에 나오므로 git notes 로 기록
+func (g gitRevisionStore) SetRestartResult(fullPath, id, result string) error {
+    if _, err := g.relPath(fullPath); err != nil {
+        return fileRevisionStore{}.SetRestartResult(fullPath, id, result)
+    }
+    if !gitRevPattern.MatchString(id) {
+        return fmt.Errorf("잘못된 리비전: %s", id)
+    }
+    gitMu.Lock()
+    defer gitMu.Unlock()
+    _, err := g.git("notes", "add", "-f", "-m", result, id)
+    return err
+}
+
+func (g gitRevisionStore) Read(fullPath, id string) ([]byte, error) {
+    rel, err := g.relPath(fullPath)
+    if err != nil {
+        return fileRevisionStore{}.Read(fullPath, id)
+    }
+    if !gitRevPattern.MatchString(id) {
+        return nil, fmt.Errorf("잘못된 리비전: %s", id)
+    }
+    gitMu.Lock()
+    defer gitMu.Unlock()
+    return g.git("show", id+":"+rel)
+}
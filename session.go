@@ -0,0 +1,180 @@
+package main
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "fmt"
+    "log"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-contrib/sessions"
+    "github.com/gin-contrib/sessions/cookie"
+    "github.com/gin-gonic/gin"
+)
+
+// ------------------------------------------------------
+// 28. 세션 키 및 쿠키 설정
+// ------------------------------------------------------
+
+// 세션 쿠키 서명/암호화 키를 저장하는 파일 (.env 에 없으면 만들어 추가한다)
+const envFile = ".env"
+
+// .env 의 세션 관련 설정
+type sessionConfig struct {
+    AuthKey  []byte        // 서명(HMAC) 키, 64바이트
+    EncKey   []byte        // 암호화(AES-256) 키, 32바이트
+    Idle     time.Duration // 이 시간 동안 요청이 없으면 만료 (0 이면 사용 안 함)
+    Absolute time.Duration // 로그인 후 이 시간이 지나면 만료 (쿠키 MaxAge 로도 사용)
+    Secure   bool          // HTTPS 에서만 쿠키 전송
+    SameSite http.SameSite
+}
+
+var sessionCfg sessionConfig
+
+// 세션에 저장하는 값
+const (
+    sessionUserKey     = "user_email"
+    sessionLoginAtKey  = "login_at"  // 로그인 시각 (unix 초)
+    sessionLastSeenKey = "last_seen" // 마지막 요청 시각 (unix 초)
+)
+
+// last_seen 갱신 간격 (요청마다 쿠키를 다시 쓰지 않도록)
+const sessionTouchInterval = time.Minute
+
+// loadSessionConfig: .env 의 session_auth_key, session_enc_key (hex), session_idle_timeout,
+// session_absolute_timeout (초), session_secure (true/false, 기본: public_url 이 https 면 true),
+// session_samesite (lax/strict/none) 를 읽는다. 키가 없으면 새로 만들어 .env 에 저장한다.
+func loadSessionConfig() (sessionConfig, error) {
+    cfg := sessionConfig{
+        Idle:     30 * time.Minute,
+        Absolute: 12 * time.Hour,
+        Secure:   strings.HasPrefix(publicURL, "https://"),
+        SameSite: http.SameSiteLaxMode,
+    }
+    var err error
+    if cfg.AuthKey, err = sessionKey("session_auth_key", 64); err != nil {
+        return cfg, err
+    }
+    if cfg.EncKey, err = sessionKey("session_enc_key", 32); err != nil {
+        return cfg, err
+    }
+    if cfg.Idle, err = envSeconds("session_idle_timeout", cfg.Idle); err != nil {
+        return cfg, err
+    }
+    if cfg.Absolute, err = envSeconds("session_absolute_timeout", cfg.Absolute); err != nil {
+        return cfg, err
+    }
+    if cfg.Absolute <= 0 {
+        return cfg, fmt.Errorf("session_absolute_timeout 은 0 보다 커야 합니다.")
+    }
+    if v := os.Getenv("session_secure"); v != "" {
+        if cfg.Secure, err = strconv.ParseBool(v); err != nil {
+            return cfg, fmt.Errorf("session_secure 는 true 또는 false 여야 합니다: %s", v)
+        }
+    }
+    switch v := strings.ToLower(os.Getenv("session_samesite")); v {
+    case "", "lax":
+    case "strict":
+        cfg.SameSite = http.SameSiteStrictMode
+    case "none":
+        // SameSite=None 은 브라우저가 Secure 쿠키에만 허용한다
+        if !cfg.Secure {
+            return cfg, fmt.Errorf("session_samesite=none 은 session_secure=true 일 때만 사용할 수 있습니다.")
+        }
+        cfg.SameSite = http.SameSiteNoneMode
+    default:
+        return cfg, fmt.Errorf("session_samesite 는 lax, strict, none 중 하나여야 합니다: %s", v)
+    }
+    return cfg, nil
+}
+
+// sessionKey: .env 의 hex 키를 읽고, 없으면 size 바이트 랜덤 키를 만들어 .env 에 추가
+func sessionKey(name string, size int) ([]byte, error) {
+    if v := os.Getenv(name); v != "" {
+        key, err := hex.DecodeString(v)
+        if err != nil || len(key) != size {
+            return nil, fmt.Errorf("%s 는 %d바이트 hex 문자열이어야 합니다.", name, size)
+        }
+        return key, nil
+    }
+    key := make([]byte, size)
+    if _, err := rand.Read(key); err != nil {
+        return nil, err
+    }
+    encoded := hex.EncodeToString(key)
+    f, err := os.OpenFile(envFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+    if err != nil {
+        return nil, fmt.Errorf("%s 저장 실패: %v", name, err)
+    }
+    defer f.Close()
+    if _, err := fmt.Fprintf(f, "\n%s=\"%s\"\n", name, encoded); err != nil {
+        return nil, fmt.Errorf("%s 저장 실패: %v", name, err)
+    }
+    os.Setenv(name, encoded)
+    log.Printf("%s 가 없어 새로 만들어 %s 에 저장했습니다.", name, envFile)
+    return key, nil
+}
+
+// envSeconds: 초 단위 설정값 (없으면 def)
+func envSeconds(name string, def time.Duration) (time.Duration, error) {
+    v := os.Getenv(name)
+    if v == "" {
+        return def, nil
+    }
+    n, err := strconv.Atoi(v)
+    if err != nil || n < 0 {
+        return 0, fmt.Errorf("%s 값이 잘못되었습니다: %s", name, v)
+    }
+    return time.Duration(n) * time.Second, nil
+}
+
+// newSessionStore: 설정으로 쿠키 저장소 생성
+func newSessionStore(cfg sessionConfig) sessions.Store {
+    store := cookie.NewStore(cfg.AuthKey, cfg.EncKey)
+    store.Options(sessions.Options{
+        Path:     "/",
+        MaxAge:   int(cfg.Absolute.Seconds()),
+        HttpOnly: true,
+        Secure:   cfg.Secure,
+        SameSite: cfg.SameSite,
+    })
+    return store
+}
+
+// startSession: 로그인 처리 (이전 세션 값은 모두 지움)
+func startSession(c *gin.Context, email string) {
+    now := time.Now().Unix()
+    sess := sessions.Default(c)
+    sess.Clear()
+    sess.Set(sessionUserKey, email)
+    sess.Set(sessionLoginAtKey, now)
+    sess.Set(sessionLastSeenKey, now)
+    sess.Save()
+}
+
+// checkSession: 만료된 세션이면 지우고 false. 유효하면 마지막 요청 시각을 갱신한다.
+func checkSession(c *gin.Context) bool {
+    sess := sessions.Default(c)
+    if sess.Get(sessionUserKey) == nil {
+        return false
+    }
+    now := time.Now()
+    loginAt, _ := sess.Get(sessionLoginAtKey).(int64)
+    lastSeen, _ := sess.Get(sessionLastSeenKey).(int64)
+    expired := loginAt == 0 || now.Sub(time.Unix(loginAt, 0)) > sessionCfg.Absolute ||
+        (sessionCfg.Idle > 0 && now.Sub(time.Unix(lastSeen, 0)) > sessionCfg.Idle)
+    if expired {
+        sess.Clear()
+        sess.Save()
+        return false
+    }
+    if now.Sub(time.Unix(lastSeen, 0)) >= sessionTouchInterval {
+        sess.Set(sessionLastSeenKey, now.Unix())
+        sess.Save()
+    }
+    return true
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "net/http"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "sync"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ------------------------------------------------------
+// 13. 서비스 상태 조회 (compose ps)
+// ------------------------------------------------------
+
+// 서비스(컨테이너) 한 개의 상태
+type serviceStatus struct {
+    Service  string   `json:"service"`
+    Name     string   `json:"name"`
+    State    string   `json:"state"`  // running, exited, restarting, missing ...
+    Health   string   `json:"health"` // healthy, unhealthy, starting 또는 빈 값
+    ExitCode int      `json:"exitCode"`
+    Status   string   `json:"status"`
+    Image    string   `json:"image"`
+    Ports    []string `json:"ports"`
+}
+
+// compose 파일 하나(프로젝트)의 상태
+type projectStatus struct {
+    Path     string          `json:"path"`
+    State    string          `json:"state"` // up, degraded, stopped
+    Services []serviceStatus `json:"services"`
+    Error    string          `json:"error,omitempty"`
+}
+
+// "compose ps --format json" 의 출력 한 줄(또는 배열 요소)
+type composePsEntry struct {
+    Name       string
+    Service    string
+    State      string
+    Health     string
+    ExitCode   int
+    Status     string
+    Image      string
+    Publishers []struct {
+        URL           string
+        TargetPort    int
+        PublishedPort int
+        Protocol      string
+    }
+}
+
+// composeExec: composeCommand 를 이용해 [파일]이 있는 디렉토리에서 실행할 명령 생성
+func composeExec(filePath string, args ...string) *exec.Cmd {
+    return composeExecContext(context.Background(), filePath, args...)
+}
+
+func composeExecContext(ctx context.Context, filePath string, args ...string) *exec.Cmd {
+    // 예) composeCommand = "docker compose" -> "docker" + ["compose", "-f", 파일, args...]
+    parts := strings.Split(composeCommand, " ")
+    full := append([]string{}, parts[1:]...)
+    // 등록 프로젝트에 지정된 env 파일
+    if p := projectForFile(filePath); p != nil {
+        for _, env := range p.EnvFiles {
+            full = append(full, "--env-file", env)
+        }
+    }
+    full = append(full, "-f", filepath.Base(filePath))
+    full = append(full, args...)
+    cmd := exec.CommandContext(ctx, parts[0], full...)
+    cmd.Dir = filepath.Dir(filePath)
+    return cmd
+}
+
+// parseComposePs: 버전에 따라 JSON 배열 또는 줄 단위 JSON 으로 나오는 ps 출력을 파싱
+func parseComposePs(out []byte) ([]composePsEntry, error) {
+    out = bytes.TrimSpace(out)
+    var entries []composePsEntry
+    if len(out) == 0 {
+        return entries, nil
+    }
+    if out[0] == '[' {
+        if err := json.Unmarshal(out, &entries); err != nil {
+            return nil, err
+        }
+        return entries, nil
+    }
+    for _, line := range bytes.Split(out, []byte("\n")) {
+        line = bytes.TrimSpace(line)
+        if len(line) == 0 {
+            continue
+        }
+        var e composePsEntry
+        if err := json.Unmarshal(line, &e); err != nil {
+            return nil, err
+        }
+        entries = append(entries, e)
+    }
+    return entries, nil
+}
+
+// composeServices: compose 파일에 정의된 서비스 이름 목록
+func composeServices(filePath string) ([]string, error) {
+    out, err := composeExec(filePath, "config", "--services").Output()
+    if err != nil {
+        return nil, err
+    }
+    var services []string
+    for _, s := range strings.Split(string(out), "\n") {
+        if s = strings.TrimSpace(s); s != "" {
+            services = append(services, s)
+        }
+    }
+    return services, nil
+}
+
+// composeStatus: compose 파일의 서비스별 상태를 조회
+func composeStatus(filePath string) ([]serviceStatus, error) {
+    if composeCommand == "" {
+        return nil, fmt.Errorf("docker compose 명령이 감지되지 않았습니다.")
+    }
+    out, err := composeExec(filePath, "ps", "-a", "--format", "json").Output()
+    if err != nil {
+        if ee, ok := err.(*exec.ExitError); ok {
+            return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(ee.Stderr)))
+        }
+        return nil, err
+    }
+    entries, err := parseComposePs(out)
+    if err != nil {
+        return nil, fmt.Errorf("ps 출력 파싱 오류: %v", err)
+    }
+
+    var result []serviceStatus
+    seen := make(map[string]bool)
+    for _, e := range entries {
+        st := serviceStatus{
+            Service:  e.Service,
+            Name:     e.Name,
+            State:    e.State,
+            Health:   e.Health,
+            ExitCode: e.ExitCode,
+            Status:   e.Status,
+            Image:    e.Image,
+        }
+        for _, p := range e.Publishers {
+            if p.PublishedPort == 0 {
+                st.Ports = append(st.Ports, fmt.Sprintf("%d/%s", p.TargetPort, p.Protocol))
+                continue
+            }
+            st.Ports = append(st.Ports, fmt.Sprintf("%s:%d->%d/%s", p.URL, p.PublishedPort, p.TargetPort, p.Protocol))
+        }
+        seen[e.Service] = true
+        result = append(result, st)
+    }
+
+    // 컨테이너가 아예 없는 서비스는 missing 으로 표시
+    if services, err := composeServices(filePath); err == nil {
+        for _, s := range services {
+            if !seen[s] {
+                result = append(result, serviceStatus{Service: s, State: "missing"})
+            }
+        }
+    }
+    return result, nil
+}
+
+// summarizeStatus: 서비스 상태들로 프로젝트 전체 상태(up/degraded/stopped) 계산
+func summarizeStatus(services []serviceStatus) string {
+    running, healthy := 0, 0
+    for _, s := range services {
+        if s.State == "running" {
+            running++
+            if s.Health == "" || s.Health == "healthy" {
+                healthy++
+            }
+        }
+    }
+    switch {
+    case running == 0:
+        return "stopped"
+    case healthy == len(services):
+        return "up"
+    default:
+        return "degraded"
+    }
+}
+
+// 서비스 상태 조회 (AJAX)
+func statusAPI(c *gin.Context) {
+    p := c.Query("path")
+    if p == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "path 파라미터 필요"})
+        return
+    }
+    fullPath, err := resolvePath(p)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    services, err := composeStatus(fullPath)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, projectStatus{
+        Path:     p,
+        State:    summarizeStatus(services),
+        Services: services,
+    })
+}
+
+// isComposeFile: docker-compose*.yml / compose*.yaml 형태의 파일인지 확인
+func isComposeFile(name string) bool {
+    name = strings.ToLower(name)
+    ext := filepath.Ext(name)
+    if ext != ".yml" && ext != ".yaml" {
+        return false
+    }
+    return strings.HasPrefix(name, "docker-compose") || strings.HasPrefix(name, "compose")
+}
+
+// 상태 개요에서 동시에 실행하는 compose ps 수
+const statusOverviewConcurrency = 4
+
+// 전체 스택 상태 개요 (baseDir 하위 디렉토리의 모든 compose 파일 + 등록 프로젝트)
+// 경로는 resolvePath 로 확인하고 (baseDir 밖을 가리키는 링크는 제외), ps 는 몇 개씩 동시에 실행한다.
+func statusOverviewAPI(c *gin.Context) {
+    dirs, err := ioutil.ReadDir(baseDir)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    u := currentUser(c)
+    var targets []string
+    for _, d := range dirs {
+        if !d.IsDir() || !hasProjectPermission(u, d.Name(), permRead) {
+            continue
+        }
+        dirPath, err := resolvePath(d.Name())
+        if err != nil {
+            continue
+        }
+        infos, err := ioutil.ReadDir(dirPath)
+        if err != nil {
+            continue
+        }
+        for _, f := range infos {
+            if f.IsDir() || !isComposeFile(f.Name()) {
+                continue
+            }
+            targets = append(targets, filepath.Join(d.Name(), f.Name()))
+        }
+    }
+    // 등록된 프로젝트의 compose 파일
+    for _, p := range listProjects() {
+        if hasProjectPermission(u, projectPrefix+p.Name, permRead) {
+            targets = append(targets, projectPrefix+p.Name+"/"+filepath.Base(p.ComposeFile))
+        }
+    }
+
+    result := make([]projectStatus, len(targets))
+    sem := make(chan struct{}, statusOverviewConcurrency)
+    var wg sync.WaitGroup
+    for i, rel := range targets {
+        wg.Add(1)
+        go func(i int, rel string) {
+            defer wg.Done()
+            sem <- struct{}{}
+            defer func() { <-sem }()
+            result[i] = overviewStatus(rel)
+        }(i, rel)
+    }
+    wg.Wait()
+
+    // baseDir 의 파일 중 resolvePath 가 거부한 것(밖을 가리키는 링크 등)은 목록에서 뺀다
+    shown := []projectStatus{}
+    for _, ps := range result {
+        if ps.Path != "" {
+            shown = append(shown, ps)
+        }
+    }
+    c.JSON(http.StatusOK, shown)
+}
+
+// overviewStatus: 웹 경로 rel 의 compose 상태. baseDir 의 경로가 거부되면 빈 값을 돌려준다.
+func overviewStatus(rel string) projectStatus {
+    ps := projectStatus{Path: rel}
+    fullPath, err := resolvePath(rel)
+    if err != nil {
+        if !strings.HasPrefix(rel, projectPrefix) {
+            return projectStatus{}
+        }
+        // 등록 프로젝트는 설정 문제를 보이도록 오류로 표시
+        ps.State = "unknown"
+        ps.Error = err.Error()
+        return ps
+    }
+    services, err := composeStatus(fullPath)
+    if err != nil {
+        ps.State = "unknown"
+        ps.Error = err.Error()
+    } else {
+        ps.State = summarizeStatus(services)
+        ps.Services = services
+    }
+    return ps
+}
+
+// ------------------------------------------------------
+// 15. 서비스 단위 액션 (start/stop/restart/pull/recreate)
+// ------------------------------------------------------
+
+// 액션별 compose 하위 명령 (서비스명은 마지막 인자로 추가)
+var serviceActions = map[string][]string{
+    "start":    {"start"},
+    "stop":     {"stop"},
+    "restart":  {"restart"},
+    "pull":     {"pull"},
+    "recreate": {"up", "-d", "--no-deps", "--force-recreate"},
+}
+
+// composeServiceAction: 단일 서비스에 대해 action 실행 (출력은 w 로 기록)
+func composeServiceAction(w io.Writer, filePath, service, action string) error {
+    if composeCommand == "" {
+        return fmt.Errorf("docker compose 명령이 감지되지 않았습니다.")
+    }
+    sub, ok := serviceActions[action]
+    if !ok {
+        return fmt.Errorf("지원하지 않는 액션: %s", action)
+    }
+    args := append(append([]string{}, sub...), service)
+    return runCompose(w, filePath, args...)
+}
+
+// 서비스 액션 실행 (AJAX)
+func serviceActionAPI(c *gin.Context) {
+    p := c.PostForm("path")
+    service := c.PostForm("service")
+    action := c.PostForm("action")
+    if p == "" || service == "" || action == "" {
+        c.String(http.StatusBadRequest, "path, service, action 필요")
+        return
+    }
+    if _, ok := serviceActions[action]; !ok {
+        c.String(http.StatusBadRequest, fmt.Sprintf("지원하지 않는 액션: %s", action))
+        return
+    }
+    fullPath, err := resolvePath(p)
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
+
+    // 정의되지 않은 서비스명은 거부
+    services, err := composeServices(fullPath)
+    if err != nil {
+        c.String(http.StatusInternalServerError, fmt.Sprintf("서비스 목록 조회 오류: %v", err))
+        return
+    }
+    found := false
+    for _, s := range services {
+        if s == service {
+            found = true
+            break
+        }
+    }
+    if !found {
+        c.String(http.StatusBadRequest, fmt.Sprintf("정의되지 않은 서비스: %s", service))
+        return
+    }
+
+    ev := newAudit(c, "service:"+action, p)
+    ev.Detail = service
+    j := jobs.submit("service:"+action, p+" ("+service+")", projectKey(fullPath), currentUser(c).Email, func(w io.Writer) error {
+        err := composeServiceAction(w, fullPath, service, action)
+        ev := ev
+        ev.Job = jobID(w)
+        ev.record(err)
+        return err
+    })
+    ev.queued(j)
+    respondJob(c, j, fmt.Sprintf("%s %s 작업을 시작했습니다.", service, action))
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "os"
+    "path/filepath"
+    "runtime"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+func TestParseComposePs(t *testing.T) {
+    tests := []struct {
+        name     string
+        out      string
+        services []string
+        wantErr  bool
+    }{
+        {"빈 출력", "", nil, false},
+        {"공백만", "  \n", nil, false},
+        // docker compose v2.21 이후: 컨테이너마다 한 줄
+        {"JSON lines", `{"Name":"app-web-1","Service":"web","State":"running","Health":"healthy","ExitCode":0}
+{"Name":"app-db-1","Service":"db","State":"exited","ExitCode":1}
+`, []string{"web", "db"}, false},
+        {"JSON lines (빈 줄, CRLF)", "{\"Service\":\"web\",\"State\":\"running\"}\r\n\r\n{\"Service\":\"db\",\"State\":\"running\"}\r\n", []string{"web", "db"}, false},
+        // 이전 v2: JSON 배열 하나
+        {"JSON 배열", `[{"Name":"app-web-1","Service":"web","State":"running","Publishers":[{"URL":"0.0.0.0","TargetPort":80,"PublishedPort":8080,"Protocol":"tcp"}]},{"Name":"app-db-1","Service":"db","State":"running"}]`, []string{"web", "db"}, false},
+        {"빈 배열", "[]", nil, false},
+        {"깨진 배열", `[{"Service":"web"`, nil, true},
+        {"깨진 줄", "{\"Service\":\"web\"}\nnot json\n", nil, true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            entries, err := parseComposePs([]byte(tt.out))
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+            }
+            if tt.wantErr {
+                return
+            }
+            if len(entries) != len(tt.services) {
+                t.Fatalf("항목 %d개, want %d (%+v)", len(entries), len(tt.services), entries)
+            }
+            for i, s := range tt.services {
+                if entries[i].Service != s {
+                    t.Errorf("entries[%d].Service = %q, want %q", i, entries[i].Service, s)
+                }
+            }
+        })
+    }
+
+    // 필드 값까지 확인
+    entries, _ := parseComposePs([]byte(`{"Name":"app-db-1","Service":"db","State":"exited","Health":"","ExitCode":137,"Image":"postgres:16"}`))
+    if e := entries[0]; e.Name != "app-db-1" || e.State != "exited" || e.ExitCode != 137 || e.Image != "postgres:16" {
+        t.Errorf("필드 파싱 결과가 다름: %+v", e)
+    }
+    entries, _ = parseComposePs([]byte(`[{"Service":"web","Publishers":[{"URL":"0.0.0.0","TargetPort":80,"PublishedPort":8080,"Protocol":"tcp"}]}]`))
+    if p := entries[0].Publishers; len(p) != 1 || p[0].PublishedPort != 8080 || p[0].TargetPort != 80 || p[0].Protocol != "tcp" {
+        t.Errorf("Publishers 파싱 결과가 다름: %+v", p)
+    }
+}
+
+func TestSummarizeStatus(t *testing.T) {
+    tests := []struct {
+        name     string
+        services []serviceStatus
+        want     string
+    }{
+        {"서비스 없음", nil, "stopped"},
+        {"모두 중지", []serviceStatus{{State: "exited"}, {State: "missing"}}, "stopped"},
+        {"모두 실행 (헬스체크 없음)", []serviceStatus{{State: "running"}, {State: "running"}}, "up"},
+        {"모두 healthy", []serviceStatus{{State: "running", Health: "healthy"}}, "up"},
+        {"starting 포함", []serviceStatus{{State: "running", Health: "starting"}, {State: "running"}}, "degraded"},
+        {"일부 중지", []serviceStatus{{State: "running"}, {State: "exited"}}, "degraded"},
+        {"unhealthy 포함", []serviceStatus{{State: "running", Health: "unhealthy"}}, "degraded"},
+    }
+    for _, tt := range tests {
+        if got := summarizeStatus(tt.services); got != tt.want {
+            t.Errorf("%s: summarizeStatus = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
+
+// fakeComposeOverview: 어느 디렉토리에서든 web 서비스 하나가 실행 중이라고 답하는 가짜 compose 명령.
+// ps 가 실행될 때마다 그 순간 동시에 실행 중인 ps 수를 dir/concurrency 에 한 줄씩 남긴다.
+func fakeComposeOverview(t *testing.T) (logFile string) {
+    if runtime.GOOS == "windows" {
+        t.Skip("sh 스크립트 필요")
+    }
+    dir := t.TempDir()
+    running := filepath.Join(dir, "running")
+    if err := os.Mkdir(running, 0755); err != nil {
+        t.Fatal(err)
+    }
+    logFile = filepath.Join(dir, "concurrency")
+    script := fmt.Sprintf(`#!/bin/sh
+case "$3" in
+ps)
+    touch %[1]s/$$
+    ls %[1]s | wc -l >> %[2]s
+    sleep 0.1
+    rm %[1]s/$$
+    echo '{"Service":"web","State":"running"}' ;;
+config)
+    echo web ;;
+esac
+`, running, logFile)
+    if err := ioutil.WriteFile(filepath.Join(dir, "compose"), []byte(script), 0755); err != nil {
+        t.Fatal(err)
+    }
+    old := composeCommand
+    composeCommand = filepath.Join(dir, "compose")
+    t.Cleanup(func() { composeCommand = old })
+    return logFile
+}
+
+func TestStatusOverview(t *testing.T) {
+    setupRBACTree(t)
+    setTestUsers(t, rbacAdmin, rbacTeam, rbacExtAdmin, rbacNone)
+    logFile := fakeComposeOverview(t)
+
+    // 동시 실행 제한을 넘는 수의 프로젝트
+    for i := 0; i < 6; i++ {
+        d := filepath.Join(baseDir, fmt.Sprintf("extra%d", i))
+        if err := os.MkdirAll(d, 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := ioutil.WriteFile(filepath.Join(d, "compose.yaml"), []byte("services: {}\n"), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    // baseDir 밖을 가리키는 compose 파일 링크는 조회하지 않는다
+    outside := t.TempDir()
+    if err := ioutil.WriteFile(filepath.Join(outside, "docker-compose.yml"), []byte("services: {}\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.Symlink(filepath.Join(outside, "docker-compose.yml"), filepath.Join(baseDir, "payments", "compose.outside.yml")); err != nil {
+        t.Fatal(err)
+    }
+    // 파일이 없는 등록 프로젝트도 목록에는 나온다
+    registry.Projects = append(registry.Projects, &project{Name: "gone", ComposeFile: filepath.Join(outside, "gone", "docker-compose.yml")})
+
+    r := testRouter()
+    r.GET("/overview", statusOverviewAPI)
+    overview := func(u *User) map[string]projectStatus {
+        w := testRequest(r, http.MethodGet, "/overview", nil, map[string]string{testUserHeader: u.Email})
+        if w.Code != http.StatusOK {
+            t.Fatalf("%s: %d %s", u.Email, w.Code, w.Body.String())
+        }
+        var list []projectStatus
+        if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+            t.Fatal(err)
+        }
+        got := make(map[string]projectStatus)
+        for _, ps := range list {
+            got[ps.Path] = ps
+        }
+        return got
+    }
+
+    got := overview(rbacAdmin)
+    want := []string{"payments/docker-compose.yml", "shared/docker-compose.yml", "other/docker-compose.yml", "@ext/stack.yml", "@gone/docker-compose.yml"}
+    for i := 0; i < 6; i++ {
+        want = append(want, fmt.Sprintf("extra%d/compose.yaml", i))
+    }
+    if len(got) != len(want) {
+        t.Fatalf("항목 %d개, want %d: %v", len(got), len(want), got)
+    }
+    for _, p := range want {
+        if _, ok := got[p]; !ok {
+            t.Errorf("%s 가 없음", p)
+        }
+    }
+    for p, ps := range got {
+        if p == "@gone/docker-compose.yml" {
+            continue
+        }
+        if ps.State != "up" || len(ps.Services) != 1 || ps.Services[0].Service != "web" {
+            t.Errorf("%s: %+v", p, ps)
+        }
+    }
+    if ps := got["@gone/docker-compose.yml"]; ps.State != "unknown" || ps.Error == "" {
+        t.Errorf("없는 등록 프로젝트: %+v", ps)
+    }
+
+    // 동시에 실행한 ps 수는 제한을 넘지 않는다
+    data, err := ioutil.ReadFile(logFile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, line := range strings.Fields(string(data)) {
+        if n, _ := strconv.Atoi(line); n > statusOverviewConcurrency {
+            t.Fatalf("동시에 ps %d개 실행 (최대 %d)", n, statusOverviewConcurrency)
+        }
+    }
+
+    // 읽기 권한이 있는 프로젝트만
+    for _, tt := range []struct {
+        u    *User
+        want []string
+    }{
+        {rbacTeam, []string{"payments/docker-compose.yml", "shared/docker-compose.yml"}},
+        {rbacExtAdmin, want}, // 전체 viewer
+        {rbacNone, nil},
+    } {
+        got := overview(tt.u)
+        if len(got) != len(tt.want) {
+            t.Errorf("%s: %v", tt.u.Email, got)
+        }
+        for _, p := range tt.want {
+            if _, ok := got[p]; !ok {
+                t.Errorf("%s: %s 가 없음", tt.u.Email, p)
+            }
+        }
+    }
+}
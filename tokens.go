@@ -0,0 +1,313 @@
+package main
+
+import (
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "log"
+    "net/http"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ------------------------------------------------------
+// 32. 개인 API 토큰 (스크립트/CI 용, Authorization: Bearer)
+// ------------------------------------------------------
+
+// 토큰 원문 접두어 (로그나 설정 파일에서 알아보기 쉽도록)
+const apiTokenPrefix = "dcw_"
+
+// 토큰 유효 기간 (일)
+const (
+    apiTokenDefaultDays = 90
+    apiTokenMaxDays     = 365
+)
+
+// last_used 를 파일에 다시 쓰는 최소 간격
+const apiTokenTouchInterval = time.Minute
+
+// 토큰 권한 범위. 조회(read)는 항상 포함되며, 실제 권한은 소유자 역할과 범위가 모두 허용해야 한다.
+var apiTokenScopes = []string{permRead, permOperate, permEdit, permAdmin}
+
+type apiToken struct {
+    ID       string    `json:"id"`
+    Name     string    `json:"name"`
+    Owner    string    `json:"owner"`
+    Hash     string    `json:"hash"` // 토큰 원문의 SHA-256 (원문은 만들 때 한 번만 보여줌)
+    Scopes   []string  `json:"scopes"`
+    Created  time.Time `json:"created"`
+    Expires  time.Time `json:"expires"`
+    LastUsed time.Time `json:"lastUsed,omitempty"`
+}
+
+// Expired: 만료 여부 (템플릿에서도 사용)
+func (t *apiToken) Expired() bool {
+    return !time.Now().Before(t.Expires)
+}
+
+func (t *apiToken) allows(perm string) bool {
+    if perm == permRead {
+        return true
+    }
+    for _, s := range t.Scopes {
+        if s == perm {
+            return true
+        }
+    }
+    return false
+}
+
+const tokenFile = ".tokens"
+
+var (
+    tokensMu  sync.Mutex
+    apiTokens []*apiToken
+)
+
+// gin 컨텍스트에 인증한 토큰을 담는 키
+const ctxAPIToken = "api_token"
+
+func loadTokens() error {
+    data, err := ioutil.ReadFile(tokenFile)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil // .tokens 파일이 없으면 그냥 반환
+        }
+        return err
+    }
+    tokensMu.Lock()
+    defer tokensMu.Unlock()
+    return json.Unmarshal(data, &apiTokens)
+}
+
+// saveTokens: tokensMu 를 잡은 상태에서 호출
+func saveTokens() error {
+    data, err := json.MarshalIndent(apiTokens, "", "  ")
+    if err != nil {
+        return err
+    }
+    return ioutil.WriteFile(tokenFile, data, 0600)
+}
+
+func hashAPIToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+// authenticateToken: Bearer 토큰 확인. 유효하면 토큰 정보 (마지막 사용 시각도 갱신)
+func authenticateToken(raw string) (*apiToken, error) {
+    if !strings.HasPrefix(raw, apiTokenPrefix) {
+        return nil, errors.New("잘못된 토큰 형식")
+    }
+    h := hashAPIToken(raw)
+    tokensMu.Lock()
+    defer tokensMu.Unlock()
+    for _, t := range apiTokens {
+        if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(h)) != 1 {
+            continue
+        }
+        if t.Expired() {
+            return nil, errors.New("만료된 토큰")
+        }
+        if users[t.Owner] == nil {
+            return nil, errors.New("소유자가 없는 토큰")
+        }
+        if now := time.Now(); now.Sub(t.LastUsed) >= apiTokenTouchInterval {
+            t.LastUsed = now
+            if err := saveTokens(); err != nil {
+                log.Printf("[경고] 토큰 사용 시각 저장 실패: %v", err)
+            }
+        }
+        copied := *t
+        return &copied, nil
+    }
+    return nil, errors.New("등록되지 않은 토큰")
+}
+
+// bearerToken: Authorization: Bearer 헤더의 토큰 (없으면 "")
+func bearerToken(c *gin.Context) string {
+    h := c.GetHeader("Authorization")
+    if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+        return strings.TrimSpace(h[7:])
+    }
+    return ""
+}
+
+// requestToken: 이번 요청을 인증한 토큰 (세션 로그인이면 nil)
+func requestToken(c *gin.Context) *apiToken {
+    if v, ok := c.Get(ctxAPIToken); ok {
+        return v.(*apiToken)
+    }
+    return nil
+}
+
+// tokenAllows: 토큰으로 인증한 요청이면 토큰 범위에 perm 이 있는지 확인 (세션 로그인은 항상 true)
+func tokenAllows(c *gin.Context, perm string) bool {
+    t := requestToken(c)
+    return t == nil || t.allows(perm)
+}
+
+// requireSession: 토큰 관리 등 브라우저 로그인으로만 할 수 있는 작업
+func requireSession() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if requestToken(c) != nil {
+            c.String(http.StatusForbidden, "API 토큰으로는 사용할 수 없는 기능입니다.")
+            c.Abort()
+            return
+        }
+        c.Next()
+    }
+}
+
+// parseScopes: 폼의 scopes 값 검증 (read 는 항상 포함)
+func parseScopes(values []string) ([]string, error) {
+    set := map[string]bool{permRead: true}
+    for _, v := range values {
+        for _, s := range strings.Split(v, ",") {
+            s = strings.TrimSpace(s)
+            if s == "" {
+                continue
+            }
+            valid := false
+            for _, known := range apiTokenScopes {
+                if s == known {
+                    valid = true
+                }
+            }
+            if !valid {
+                return nil, fmt.Errorf("알 수 없는 권한 범위: %s", s)
+            }
+            set[s] = true
+        }
+    }
+    var scopes []string
+    for _, s := range apiTokenScopes {
+        if set[s] {
+            scopes = append(scopes, s)
+        }
+    }
+    return scopes, nil
+}
+
+// 토큰 관리 페이지: 내 토큰 목록 (어드민은 전체)
+func tokensPage(c *gin.Context) {
+    renderTokensPage(c, http.StatusOK, "", "")
+}
+
+func renderTokensPage(c *gin.Context, status int, created, message string) {
+    u := currentUser(c)
+    tokensMu.Lock()
+    var list []apiToken
+    for _, t := range apiTokens {
+        if t.Owner == u.Email || isAdmin(u) {
+            list = append(list, *t)
+        }
+    }
+    tokensMu.Unlock()
+    sort.Slice(list, func(i, j int) bool { return list[i].Created.After(list[j].Created) })
+    c.HTML(status, "tokens.html", gin.H{
+        "Email":       u.Email,
+        "IsAdmin":     isAdmin(u),
+        "Tokens":      list,
+        "Scopes":      apiTokenScopes,
+        "DefaultDays": apiTokenDefaultDays,
+        "MaxDays":     apiTokenMaxDays,
+        "Created":     created,
+        "Message":     message,
+        "CSRFToken":   csrfToken(c),
+    })
+}
+
+// 토큰 만들기: 원문은 이 응답에서만 보여준다
+func createTokenAPI(c *gin.Context) {
+    u := currentUser(c)
+    name := strings.TrimSpace(c.PostForm("name"))
+    ev := newAudit(c, "token-create", u.Email)
+    ev.Detail = name
+    if name == "" || len(name) > 64 {
+        renderTokensPage(c, http.StatusBadRequest, "", "토큰 이름이 필요합니다. (64자 이하)")
+        return
+    }
+    scopes, err := parseScopes(c.PostFormArray("scopes"))
+    if err != nil {
+        renderTokensPage(c, http.StatusBadRequest, "", err.Error())
+        return
+    }
+    days := apiTokenDefaultDays
+    if v := c.PostForm("days"); v != "" {
+        if days, err = strconv.Atoi(v); err != nil || days < 1 || days > apiTokenMaxDays {
+            renderTokensPage(c, http.StatusBadRequest, "", fmt.Sprintf("유효 기간은 1 ~ %d일이어야 합니다.", apiTokenMaxDays))
+            return
+        }
+    }
+    raw := apiTokenPrefix + randomHex(32)
+    now := time.Now()
+    t := &apiToken{
+        ID:      randomHex(6),
+        Name:    name,
+        Owner:   u.Email,
+        Hash:    hashAPIToken(raw),
+        Scopes:  scopes,
+        Created: now,
+        Expires: now.AddDate(0, 0, days),
+    }
+    ev.Detail = fmt.Sprintf("%s [%s] %d일", name, strings.Join(scopes, ","), days)
+    tokensMu.Lock()
+    apiTokens = append(apiTokens, t)
+    err = saveTokens()
+    if err != nil {
+        apiTokens = apiTokens[:len(apiTokens)-1]
+    }
+    tokensMu.Unlock()
+    ev.Token = t.ID
+    ev.record(err)
+    if err != nil {
+        c.String(http.StatusInternalServerError, fmt.Sprintf("토큰 저장 오류: %v", err))
+        return
+    }
+    renderTokensPage(c, http.StatusOK, raw, "")
+}
+
+// 토큰 폐기 (본인 토큰, 어드민은 모든 토큰)
+func revokeTokenAPI(c *gin.Context) {
+    u := currentUser(c)
+    id := c.PostForm("id")
+    ev := newAudit(c, "token-revoke", "")
+    tokensMu.Lock()
+    idx := -1
+    for i, t := range apiTokens {
+        if t.ID == id && (t.Owner == u.Email || isAdmin(u)) {
+            idx = i
+        }
+    }
+    var err error
+    if idx >= 0 {
+        t := apiTokens[idx]
+        ev.Target, ev.Detail, ev.Token = t.Owner, t.Name, t.ID
+        apiTokens = append(apiTokens[:idx:idx], apiTokens[idx+1:]...)
+        if err = saveTokens(); err != nil {
+            apiTokens = append(apiTokens[:idx], append([]*apiToken{t}, apiTokens[idx:]...)...)
+        }
+    }
+    tokensMu.Unlock()
+    if idx < 0 {
+        c.String(http.StatusNotFound, "토큰을 찾을 수 없습니다.")
+        return
+    }
+    ev.record(err)
+    if err != nil {
+        c.String(http.StatusInternalServerError, fmt.Sprintf("토큰 저장 오류: %v", err))
+        return
+    }
+    renderTokensPage(c, http.StatusOK, "", "토큰을 폐기했습니다.")
+}
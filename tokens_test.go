@@ -0,0 +1,346 @@
+package main
+
+import (
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// setTestTokens: 전역 apiTokens 를 주어진 토큰으로 바꾸고 테스트가 끝나면 되돌린다.
+// 원문 → 토큰. LastUsed 를 지금으로 두어 인증할 때 .tokens 를 쓰지 않게 한다.
+func setTestTokens(t *testing.T, list map[string]*apiToken) {
+    tokensMu.Lock()
+    old := apiTokens
+    apiTokens = nil
+    for raw, tok := range list {
+        tok.Hash = hashAPIToken(raw)
+        if tok.LastUsed.IsZero() {
+            tok.LastUsed = time.Now()
+        }
+        apiTokens = append(apiTokens, tok)
+    }
+    tokensMu.Unlock()
+    t.Cleanup(func() {
+        tokensMu.Lock()
+        apiTokens = old
+        tokensMu.Unlock()
+    })
+}
+
+func TestAPITokenAllows(t *testing.T) {
+    tests := []struct {
+        scopes []string
+        perm   string
+        want   bool
+    }{
+        {nil, permRead, true},
+        {nil, permOperate, false},
+        {[]string{permRead}, permEdit, false},
+        {[]string{permRead, permOperate}, permOperate, true},
+        {[]string{permRead, permOperate}, permEdit, false},
+        {[]string{permRead, permEdit}, permEdit, true},
+        {[]string{permRead, permOperate, permEdit}, permAdmin, false},
+        {[]string{permRead, permAdmin}, permAdmin, true},
+        {[]string{permRead, permAdmin}, permOperate, false}, // admin 범위가 다른 범위를 포함하지는 않는다
+    }
+    for _, tt := range tests {
+        tok := &apiToken{Scopes: tt.scopes}
+        if got := tok.allows(tt.perm); got != tt.want {
+            t.Errorf("scopes %v allows(%s) = %v, want %v", tt.scopes, tt.perm, got, tt.want)
+        }
+    }
+}
+
+func TestAPITokenExpired(t *testing.T) {
+    now := time.Now()
+    for _, tt := range []struct {
+        expires time.Time
+        want    bool
+    }{
+        {now.Add(time.Hour), false},
+        {now.Add(-time.Second), true},
+        {time.Time{}, true}, // 만료 시각이 없는 토큰은 쓸 수 없다
+    } {
+        if got := (&apiToken{Expires: tt.expires}).Expired(); got != tt.want {
+            t.Errorf("Expires %v: Expired = %v, want %v", tt.expires, got, tt.want)
+        }
+    }
+}
+
+func TestParseScopes(t *testing.T) {
+    tests := []struct {
+        name    string
+        values  []string
+        want    []string
+        wantErr bool
+    }{
+        {"없으면 read 만", nil, []string{permRead}, false},
+        {"빈 값", []string{"", " "}, []string{permRead}, false},
+        {"read 는 항상 포함", []string{permOperate}, []string{permRead, permOperate}, false},
+        {"정해진 순서로 정렬", []string{permAdmin, permEdit, permOperate}, []string{permRead, permOperate, permEdit, permAdmin}, false},
+        {"쉼표로 구분", []string{"edit, operate"}, []string{permRead, permOperate, permEdit}, false},
+        {"중복 제거", []string{permEdit, permEdit, "read,edit"}, []string{permRead, permEdit}, false},
+        {"알 수 없는 범위", []string{permOperate, "root"}, nil, true},
+        {"대소문자 구분", []string{"Admin"}, nil, true},
+    }
+    for _, tt := range tests {
+        got, err := parseScopes(tt.values)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+            continue
+        }
+        if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("%s: parseScopes(%q) = %v, want %v", tt.name, tt.values, got, tt.want)
+        }
+    }
+}
+
+func TestAuthenticateToken(t *testing.T) {
+    setTestUsers(t, &User{Email: "owner@test", Role: roleOperator})
+    now := time.Now()
+    setTestTokens(t, map[string]*apiToken{
+        "dcw_valid":   {ID: "valid", Owner: "owner@test", Scopes: []string{permRead, permOperate}, Expires: now.Add(time.Hour)},
+        "dcw_expired": {ID: "expired", Owner: "owner@test", Scopes: []string{permRead}, Expires: now.Add(-time.Minute)},
+        "dcw_orphan":  {ID: "orphan", Owner: "deleted@test", Scopes: []string{permRead}, Expires: now.Add(time.Hour)},
+    })
+
+    tests := []struct {
+        raw     string
+        wantID  string
+        wantErr string
+    }{
+        {"dcw_valid", "valid", ""},
+        {"dcw_expired", "", "만료된 토큰"},
+        {"dcw_orphan", "", "소유자가 없는 토큰"},
+        {"dcw_unknown", "", "등록되지 않은 토큰"},
+        {"dcw_valid ", "", "등록되지 않은 토큰"},
+        {"valid", "", "잘못된 토큰 형식"},
+        {"", "", "잘못된 토큰 형식"},
+    }
+    for _, tt := range tests {
+        got, err := authenticateToken(tt.raw)
+        if tt.wantErr != "" {
+            if err == nil || err.Error() != tt.wantErr {
+                t.Errorf("%q: err = %v, want %s", tt.raw, err, tt.wantErr)
+            }
+            continue
+        }
+        if err != nil || got.ID != tt.wantID {
+            t.Errorf("%q: got %+v, %v", tt.raw, got, err)
+        }
+    }
+
+    // 돌려준 값은 사본이라 고쳐도 저장된 토큰은 그대로
+    got, _ := authenticateToken("dcw_valid")
+    got.Scopes = append(got.Scopes, permAdmin)
+    if again, _ := authenticateToken("dcw_valid"); again.allows(permAdmin) {
+        t.Fatal("인증 결과를 고치면 저장된 토큰이 바뀜")
+    }
+}
+
+func TestAuthenticateTokenTouchesLastUsed(t *testing.T) {
+    setTestUsers(t, &User{Email: "owner@test", Role: roleViewer})
+    old := time.Now().Add(-time.Hour)
+    tok := &apiToken{ID: "t", Owner: "owner@test", Expires: time.Now().Add(time.Hour), LastUsed: old}
+    setTestTokens(t, map[string]*apiToken{"dcw_touch": tok})
+    // 사용 시각은 .tokens 에 저장된다
+    dir, _ := os.Getwd()
+    if err := os.Chdir(t.TempDir()); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { os.Chdir(dir) })
+
+    if _, err := authenticateToken("dcw_touch"); err != nil {
+        t.Fatal(err)
+    }
+    if !tok.LastUsed.After(old) {
+        t.Fatal("LastUsed 가 갱신되어야 함")
+    }
+    var saved []*apiToken
+    data, err := os.ReadFile(tokenFile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := json.Unmarshal(data, &saved); err != nil || len(saved) != 1 || !saved[0].LastUsed.Equal(tok.LastUsed) {
+        t.Fatalf("저장된 토큰: %s (%v)", data, err)
+    }
+    if strings.Contains(string(data), "dcw_touch") {
+        t.Fatal("토큰 원문이 저장되면 안 됨")
+    }
+}
+
+func TestRevokeToken(t *testing.T) {
+    owner := &User{Email: "owner@test", Role: roleOperator}
+    other := &User{Email: "other@test", Role: roleOperator}
+    admin := &User{Email: "admin@test", Role: roleAdmin}
+    setTestUsers(t, owner, other, admin)
+    expires := time.Now().Add(time.Hour)
+    setTestTokens(t, map[string]*apiToken{
+        "dcw_first":  {ID: "first", Owner: owner.Email, Expires: expires},
+        "dcw_second": {ID: "second", Owner: owner.Email, Expires: expires},
+    })
+
+    r := testRouter()
+    r.LoadHTMLGlob(filepath.Join(mustGetwd(t), "templates", "*.html"))
+    r.POST("/account/tokens/revoke", revokeTokenAPI)
+    // .tokens 와 감사 로그는 임시 디렉토리에
+    dir := mustGetwd(t)
+    if err := os.Chdir(t.TempDir()); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { os.Chdir(dir) })
+
+    revoke := func(by *User, id string) int {
+        return testRequest(r, http.MethodPost, "/account/tokens/revoke", url.Values{"id": {id}}, map[string]string{testUserHeader: by.Email}).Code
+    }
+    // 다른 사용자의 토큰은 폐기할 수 없다
+    if code := revoke(other, "first"); code != http.StatusNotFound {
+        t.Fatalf("다른 사용자의 폐기: %d", code)
+    }
+    if _, err := authenticateToken("dcw_first"); err != nil {
+        t.Fatalf("폐기되지 않은 토큰: %v", err)
+    }
+    if code := revoke(owner, "first"); code != http.StatusOK {
+        t.Fatalf("본인 폐기: %d", code)
+    }
+    if _, err := authenticateToken("dcw_first"); err == nil || err.Error() != "등록되지 않은 토큰" {
+        t.Fatalf("폐기한 토큰: %v", err)
+    }
+    // 어드민은 모든 토큰을 폐기할 수 있다
+    if code := revoke(admin, "second"); code != http.StatusOK {
+        t.Fatalf("어드민 폐기: %d", code)
+    }
+    if _, err := authenticateToken("dcw_second"); err == nil {
+        t.Fatal("어드민이 폐기한 토큰이 아직 유효함")
+    }
+    if code := revoke(owner, "second"); code != http.StatusNotFound {
+        t.Fatalf("이미 폐기한 토큰: %d", code)
+    }
+}
+
+func mustGetwd(t *testing.T) string {
+    dir, err := os.Getwd()
+    if err != nil {
+        t.Fatal(err)
+    }
+    return dir
+}
+
+// tokenTestServer: 실제 라우트(newRouter)에 토큰 테스트용 사용자/토큰/디렉토리를 준비한다
+func tokenTestServer(t *testing.T) *gin.Engine {
+    setupRBACTree(t)
+    setTestUsers(t, &User{Email: "admin@test", Role: roleAdmin})
+    expires := time.Now().Add(time.Hour)
+    setTestTokens(t, map[string]*apiToken{
+        "dcw_read":    {ID: "read", Owner: "admin@test", Scopes: []string{permRead}, Expires: expires},
+        "dcw_all":     {ID: "all", Owner: "admin@test", Scopes: apiTokenScopes, Expires: expires},
+        "dcw_expired": {ID: "expired", Owner: "admin@test", Scopes: apiTokenScopes, Expires: time.Now().Add(-time.Minute)},
+    })
+    oldCfg, oldWriter := sessionCfg, gin.DefaultWriter
+    sessionCfg = sessionConfig{
+        AuthKey:  []byte(strings.Repeat("a", 64)),
+        EncKey:   []byte(strings.Repeat("e", 32)),
+        Absolute: time.Hour,
+        SameSite: http.SameSiteLaxMode,
+    }
+    gin.SetMode(gin.TestMode)
+    gin.DefaultWriter = io.Discard // 요청 로그
+    t.Cleanup(func() { sessionCfg, gin.DefaultWriter = oldCfg, oldWriter })
+    r, err := newRouter()
+    if err != nil {
+        t.Fatal(err)
+    }
+    return r
+}
+
+func TestSessionOnlyRoutesRejectTokens(t *testing.T) {
+    r := tokenTestServer(t)
+    routes := []struct{ method, path string }{
+        {http.MethodGet, "/account/2fa"},
+        {http.MethodGet, "/account/2fa/qr.png"},
+        {http.MethodPost, "/account/2fa/enable"},
+        {http.MethodPost, "/account/2fa/recovery"},
+        {http.MethodPost, "/account/2fa/disable"},
+        {http.MethodGet, "/account/tokens"},
+        {http.MethodPost, "/account/tokens"},
+        {http.MethodPost, "/account/tokens/revoke"},
+    }
+    for _, rt := range routes {
+        // 모든 권한 범위를 가진 토큰이라도 2단계 인증/토큰 관리는 할 수 없다
+        var form url.Values
+        if rt.method == http.MethodPost {
+            form = url.Values{"name": {"new"}, "scopes": {permAdmin}, "id": {"read"}, "code": {"000000"}}
+        }
+        w := testRequest(r, rt.method, rt.path, form, map[string]string{"Authorization": "Bearer dcw_all"})
+        if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "API 토큰으로는 사용할 수 없는 기능") {
+            t.Errorf("%s %s: %d %s", rt.method, rt.path, w.Code, w.Body.String())
+        }
+    }
+    // 토큰 관리 라우트를 거치지 않았으므로 토큰은 그대로
+    tokensMu.Lock()
+    n := len(apiTokens)
+    tokensMu.Unlock()
+    if n != 3 {
+        t.Fatalf("토큰 %d개, want 3", n)
+    }
+    if _, err := authenticateToken("dcw_read"); err != nil {
+        t.Fatalf("폐기되면 안 됨: %v", err)
+    }
+}
+
+func TestTokenRoutes(t *testing.T) {
+    r := tokenTestServer(t)
+    tests := []struct {
+        name     string
+        method   string
+        path     string
+        token    string
+        want     int
+        wantCode string // /api/v1 의 JSON 오류 코드
+    }{
+        {"콘솔 읽기", http.MethodGet, "/console/api/dir", "dcw_read", http.StatusOK, ""},
+        {"v1 읽기", http.MethodGet, "/api/v1/directories", "dcw_read", http.StatusOK, ""},
+        {"콘솔: 범위 밖 operate", http.MethodPost, "/console/api/restart?path=payments", "dcw_read", http.StatusForbidden, ""},
+        {"콘솔: 범위 밖 edit", http.MethodPost, "/console/api/dir/create?dirname=new", "dcw_read", http.StatusForbidden, ""},
+        {"콘솔: 범위 밖 admin", http.MethodGet, "/console/admin/projects", "dcw_read", http.StatusForbidden, ""},
+        {"콘솔: 범위 안 admin", http.MethodGet, "/console/admin/projects", "dcw_all", http.StatusOK, ""},
+        {"v1: 범위 밖 operate", http.MethodPost, "/api/v1/restart", "dcw_read", http.StatusForbidden, apiErrTokenScope},
+        {"v1: 범위 밖 admin", http.MethodGet, "/api/v1/users", "dcw_read", http.StatusForbidden, apiErrTokenScope},
+        {"v1: 범위 안 admin", http.MethodGet, "/api/v1/users", "dcw_all", http.StatusOK, ""},
+        {"콘솔: 만료된 토큰", http.MethodGet, "/console/api/dir", "dcw_expired", http.StatusUnauthorized, ""},
+        {"v1: 만료된 토큰", http.MethodGet, "/api/v1/directories", "dcw_expired", http.StatusUnauthorized, apiErrUnauthorized},
+        {"콘솔: 등록되지 않은 토큰", http.MethodGet, "/console/api/dir", "dcw_unknown", http.StatusUnauthorized, ""},
+        {"v1: 등록되지 않은 토큰", http.MethodGet, "/api/v1/directories", "dcw_unknown", http.StatusUnauthorized, apiErrUnauthorized},
+        {"v1: 잘못된 형식", http.MethodGet, "/api/v1/directories", "read", http.StatusUnauthorized, apiErrUnauthorized},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var form url.Values
+            if tt.method == http.MethodPost {
+                form = url.Values{}
+            }
+            w := testRequest(r, tt.method, tt.path, form, map[string]string{"Authorization": "Bearer " + tt.token})
+            if w.Code != tt.want {
+                t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+            }
+            if tt.wantCode == "" {
+                return
+            }
+            var body struct {
+                Error apiErrorBody `json:"error"`
+            }
+            if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error.Code != tt.wantCode {
+                t.Fatalf("오류 코드 = %q, want %q (%s)", body.Error.Code, tt.wantCode, w.Body.String())
+            }
+        })
+    }
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha1"
+    "crypto/sha256"
+    "encoding/base32"
+    "encoding/binary"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-contrib/sessions"
+    "github.com/gin-gonic/gin"
+    qrcode "github.com/skip2/go-qrcode"
+)
+
+// ------------------------------------------------------
+// 31. 2단계 인증 (TOTP, RFC 6238)
+// ------------------------------------------------------
+
+// TOTP 파라미터 (Google Authenticator 등 일반 OTP 앱 기본값)
+const (
+    totpIssuer  = "dc_webconsole"
+    totpPeriod  = 30 // 초
+    totpDigits  = 6
+    totpSkew    = 1 // 앞뒤로 허용하는 시간 단계 수 (시계 오차)
+    totpSecretN = 20
+)
+
+// 복구 코드 개수 (한 번씩만 사용 가능)
+const recoveryCodeCount = 10
+
+// 비밀번호 확인 후 두 번째 단계를 기다리는 시간
+const pending2FATTL = 5 * time.Minute
+
+// 세션 키
+const (
+    sessionPending2FAKey   = "pending_2fa"    // 비밀번호를 통과한 이메일
+    sessionPending2FAAtKey = "pending_2fa_at" // 비밀번호 확인 시각 (unix 초)
+    sessionTOTPSetupKey    = "totp_setup"     // 등록 중인 비밀키 (확인 코드 입력 전)
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// 사용자별 마지막으로 사용한 시간 단계 (같은 코드 재사용 방지)
+var (
+    totpMu   sync.Mutex
+    totpUsed = make(map[string]int64)
+)
+
+func newTOTPSecret() string {
+    buf := make([]byte, totpSecretN)
+    rand.Read(buf)
+    return totpEncoding.EncodeToString(buf)
+}
+
+// totpCode: counter 단계의 코드 (HOTP, RFC 4226)
+func totpCode(secret string, counter int64) (string, error) {
+    key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+    if err != nil {
+        return "", err
+    }
+    var msg [8]byte
+    binary.BigEndian.PutUint64(msg[:], uint64(counter))
+    mac := hmac.New(sha1.New, key)
+    mac.Write(msg[:])
+    sum := mac.Sum(nil)
+    offset := sum[len(sum)-1] & 0x0f
+    value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+    return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// verifyTOTP: 현재 시각 기준 ±totpSkew 단계 안의 코드인지 확인. 한 번 사용한 단계와 그 이전 코드는 거부한다.
+func verifyTOTP(email, secret, code string) bool {
+    code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+    if len(code) != totpDigits {
+        return false
+    }
+    now := time.Now().Unix() / totpPeriod
+    totpMu.Lock()
+    defer totpMu.Unlock()
+    for step := now - totpSkew; step <= now+totpSkew; step++ {
+        expected, err := totpCode(secret, step)
+        if err != nil {
+            return false
+        }
+        if hmac.Equal([]byte(expected), []byte(code)) {
+            if step <= totpUsed[email] {
+                return false
+            }
+            totpUsed[email] = step
+            return true
+        }
+    }
+    return false
+}
+
+// totpURI: OTP 앱 등록용 otpauth:// URI (QR 코드 내용)
+func totpURI(email, secret string) string {
+    q := url.Values{}
+    q.Set("secret", secret)
+    q.Set("issuer", totpIssuer)
+    q.Set("algorithm", "SHA1")
+    q.Set("digits", fmt.Sprint(totpDigits))
+    q.Set("period", fmt.Sprint(totpPeriod))
+    u := url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + totpIssuer + ":" + email, RawQuery: q.Encode()}
+    return u.String()
+}
+
+// newRecoveryCodes: 화면에 한 번 보여줄 복구 코드와 .account 에 저장할 해시
+func newRecoveryCodes() (codes, hashes []string) {
+    for i := 0; i < recoveryCodeCount; i++ {
+        code := randomHex(4) + "-" + randomHex(4)
+        codes = append(codes, code)
+        hashes = append(hashes, hashRecoveryCode(code))
+    }
+    return codes, hashes
+}
+
+func hashRecoveryCode(code string) string {
+    sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+    return hex.EncodeToString(sum[:])
+}
+
+// useRecoveryCode: 맞는 복구 코드면 삭제(한 번만 사용)하고 true
+func useRecoveryCode(u *User, code string) (bool, error) {
+    h := hashRecoveryCode(code)
+    for i, stored := range u.RecoveryCodes {
+        if hmac.Equal([]byte(stored), []byte(h)) {
+            u.RecoveryCodes = append(u.RecoveryCodes[:i:i], u.RecoveryCodes[i+1:]...)
+            return true, saveAccounts()
+        }
+    }
+    return false, nil
+}
+
+// verifySecondFactor: OTP 코드 또는 복구 코드 확인. 사용한 방식("totp" 또는 "recovery-code")을 반환
+func verifySecondFactor(u *User, code string) (string, error) {
+    if verifyTOTP(u.Email, u.TOTPSecret, code) {
+        return "totp", nil
+    }
+    ok, err := useRecoveryCode(u, code)
+    if err != nil {
+        return "", fmt.Errorf("계정 저장 오류: %v", err)
+    }
+    if ok {
+        return "recovery-code", nil
+    }
+    return "", errors.New("인증 코드가 올바르지 않습니다.")
+}
+
+// ------------------------------------------------------
+// 31-1. 로그인 두 번째 단계
+// ------------------------------------------------------
+
+// beginSecondFactor: 비밀번호 확인이 끝난 사용자를 코드 입력 단계로 보낸다 (아직 로그인 아님)
+func beginSecondFactor(c *gin.Context, email string) {
+    sess := sessions.Default(c)
+    sess.Clear()
+    sess.Set(sessionPending2FAKey, email)
+    sess.Set(sessionPending2FAAtKey, time.Now().Unix())
+    sess.Save()
+    c.Redirect(http.StatusFound, "/login/2fa")
+}
+
+// pendingSecondFactor: 코드 입력을 기다리는 사용자 (없거나 시간이 지났으면 nil)
+func pendingSecondFactor(c *gin.Context) *User {
+    sess := sessions.Default(c)
+    email, _ := sess.Get(sessionPending2FAKey).(string)
+    at, _ := sess.Get(sessionPending2FAAtKey).(int64)
+    if email == "" || time.Since(time.Unix(at, 0)) > pending2FATTL {
+        return nil
+    }
+    u := users[email]
+    if u == nil || u.TOTPSecret == "" {
+        return nil
+    }
+    return u
+}
+
+func showLogin2FA(c *gin.Context) {
+    if pendingSecondFactor(c) == nil {
+        c.Redirect(http.StatusFound, "/")
+        return
+    }
+    c.HTML(http.StatusOK, "login2fa.html", gin.H{"CSRFToken": csrfToken(c)})
+}
+
+func doLogin2FA(c *gin.Context) {
+    u := pendingSecondFactor(c)
+    if u == nil {
+        c.String(http.StatusUnauthorized, "인증 시간이 지났습니다. <a href='/'>다시 로그인</a>")
+        return
+    }
+    ip := c.ClientIP()
+    ev := auditEntry{User: u.Email, IP: ip, Action: "login"}
+    if wait, _ := loginBlocked(u.Email, ip); wait > 0 {
+        ev.record(errors.New("2단계 인증 시도 제한"))
+        c.Header("Retry-After", fmt.Sprint(int(wait.Seconds()+0.999)))
+        c.String(http.StatusTooManyRequests, "인증 시도가 너무 많습니다. 잠시 후 다시 로그인하세요.")
+        return
+    }
+    method, err := verifySecondFactor(u, c.PostForm("code"))
+    if err != nil {
+        reason := "2단계 인증 실패"
+        if loginFailed(u.Email, ip) {
+            reason += " (계정 잠김)"
+        }
+        ev.record(errors.New(reason))
+        c.String(http.StatusUnauthorized, err.Error()+" <a href='/login/2fa'>다시 입력</a>")
+        return
+    }
+    loginSucceeded(u.Email, ip)
+    startSession(c, u.Email)
+    ev.Detail = method
+    ev.record(nil)
+    c.Redirect(http.StatusFound, "/console")
+}
+
+// ------------------------------------------------------
+// 31-2. 등록/해제 (/account/2fa) 및 어드민 강제
+// ------------------------------------------------------
+
+// needs2FAEnrollment: 어드민이 2단계 인증을 요구했지만 아직 등록하지 않은 사용자
+func needs2FAEnrollment(u *User) bool {
+    return u != nil && u.Require2FA && u.TOTPSecret == ""
+}
+
+// require2FAEnrollment: 등록 전에는 등록 페이지와 로그아웃만 허용 (AuthRequired 뒤에 사용)
+func require2FAEnrollment() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        path := c.Request.URL.Path
+        if !needs2FAEnrollment(currentUser(c)) || strings.HasPrefix(path, "/account/2fa") || path == "/logout" {
+            c.Next()
+            return
+        }
+        if c.Request.Method == http.MethodGet && !strings.Contains(path, "/api/") {
+            c.Redirect(http.StatusFound, "/account/2fa")
+        } else {
+            c.String(http.StatusForbidden, "2단계 인증 등록이 필요합니다. /account/2fa 에서 등록하세요.")
+        }
+        c.Abort()
+    }
+}
+
+// 2단계 인증 설정 페이지. 등록 전이면 새 비밀키와 QR 코드를 보여준다.
+func twoFactorPage(c *gin.Context) {
+    renderTwoFactorPage(c, http.StatusOK, nil, "")
+}
+
+func renderTwoFactorPage(c *gin.Context, status int, recoveryCodes []string, message string) {
+    u := currentUser(c)
+    data := gin.H{
+        "Email":         u.Email,
+        "Enabled":       u.TOTPSecret != "",
+        "Required":      u.Require2FA,
+        "Remaining":     len(u.RecoveryCodes),
+        "RecoveryCodes": recoveryCodes,
+        "Message":       message,
+        "CSRFToken":     csrfToken(c),
+    }
+    if u.TOTPSecret == "" {
+        secret := setupSecret(c)
+        data["Secret"] = secret
+        data["URI"] = totpURI(u.Email, secret)
+    }
+    c.HTML(status, "twofactor.html", data)
+}
+
+// setupSecret: 등록 중인 비밀키 (세션에 보관, 없으면 새로 만듦)
+func setupSecret(c *gin.Context) string {
+    sess := sessions.Default(c)
+    if secret, ok := sess.Get(sessionTOTPSetupKey).(string); ok && secret != "" {
+        return secret
+    }
+    secret := newTOTPSecret()
+    sess.Set(sessionTOTPSetupKey, secret)
+    sess.Save()
+    return secret
+}
+
+// 등록용 QR 코드 (PNG)
+func twoFactorQRCode(c *gin.Context) {
+    u := currentUser(c)
+    if u.TOTPSecret != "" {
+        c.String(http.StatusNotFound, "이미 2단계 인증이 등록되어 있습니다.")
+        return
+    }
+    png, err := qrcode.Encode(totpURI(u.Email, setupSecret(c)), qrcode.Medium, 256)
+    if err != nil {
+        c.String(http.StatusInternalServerError, "QR 코드 생성 오류: "+err.Error())
+        return
+    }
+    c.Header("Cache-Control", "no-store")
+    c.Data(http.StatusOK, "image/png", png)
+}
+
+// 등록 확인: OTP 앱에 표시된 코드가 맞으면 저장하고 복구 코드를 한 번 보여준다
+func enableTwoFactor(c *gin.Context) {
+    u := currentUser(c)
+    ev := newAudit(c, "2fa", u.Email)
+    ev.Detail = "enable"
+    if u.TOTPSecret != "" {
+        c.String(http.StatusBadRequest, "이미 2단계 인증이 등록되어 있습니다.")
+        return
+    }
+    secret := setupSecret(c)
+    if !verifyTOTP(u.Email, secret, c.PostForm("code")) {
+        ev.record(errors.New("코드 불일치"))
+        renderTwoFactorPage(c, http.StatusBadRequest, nil, "코드가 올바르지 않습니다. 앱의 현재 코드를 입력하세요.")
+        return
+    }
+    codes, hashes := newRecoveryCodes()
+    u.TOTPSecret, u.RecoveryCodes = secret, hashes
+    err := saveAccounts()
+    ev.record(err)
+    if err != nil {
+        u.TOTPSecret, u.RecoveryCodes = "", nil
+        c.String(http.StatusInternalServerError, fmt.Sprintf("계정 저장 오류: %v", err))
+        return
+    }
+    sess := sessions.Default(c)
+    sess.Delete(sessionTOTPSetupKey)
+    sess.Save()
+    renderTwoFactorPage(c, http.StatusOK, codes, "2단계 인증이 등록되었습니다.")
+}
+
+// 복구 코드 다시 만들기 (이전 코드는 모두 무효)
+func regenerateRecoveryCodes(c *gin.Context) {
+    u := currentUser(c)
+    ev := newAudit(c, "2fa", u.Email)
+    ev.Detail = "recovery-codes"
+    if u.TOTPSecret == "" {
+        c.String(http.StatusBadRequest, "2단계 인증이 등록되어 있지 않습니다.")
+        return
+    }
+    if _, err := verifySecondFactor(u, c.PostForm("code")); err != nil {
+        ev.record(err)
+        renderTwoFactorPage(c, http.StatusBadRequest, nil, err.Error())
+        return
+    }
+    codes, hashes := newRecoveryCodes()
+    u.RecoveryCodes = hashes
+    err := saveAccounts()
+    ev.record(err)
+    if err != nil {
+        c.String(http.StatusInternalServerError, fmt.Sprintf("계정 저장 오류: %v", err))
+        return
+    }
+    renderTwoFactorPage(c, http.StatusOK, codes, "새 복구 코드를 만들었습니다. 이전 코드는 더 이상 사용할 수 없습니다.")
+}
+
+// 2단계 인증 해제 (어드민이 요구한 사용자는 해제 불가)
+func disableTwoFactor(c *gin.Context) {
+    u := currentUser(c)
+    ev := newAudit(c, "2fa", u.Email)
+    ev.Detail = "disable"
+    if u.Require2FA {
+        c.String(http.StatusBadRequest, "어드민이 2단계 인증을 요구한 계정은 해제할 수 없습니다.")
+        return
+    }
+    if u.TOTPSecret == "" {
+        c.String(http.StatusBadRequest, "2단계 인증이 등록되어 있지 않습니다.")
+        return
+    }
+    if _, err := verifySecondFactor(u, c.PostForm("code")); err != nil {
+        ev.record(err)
+        renderTwoFactorPage(c, http.StatusBadRequest, nil, err.Error())
+        return
+    }
+    u.TOTPSecret, u.RecoveryCodes = "", nil
+    err := saveAccounts()
+    ev.record(err)
+    if err != nil {
+        c.String(http.StatusInternalServerError, fmt.Sprintf("계정 저장 오류: %v", err))
+        return
+    }
+    renderTwoFactorPage(c, http.StatusOK, nil, "2단계 인증을 해제했습니다.")
+}
+
+// 사용자에게 2단계 인증 요구/해제 (어드민)
+func updateRequire2FA(c *gin.Context) {
+    email := c.PostForm("email")
+    u, ok := users[email]
+    if !ok {
+        c.String(http.StatusBadRequest, "사용자를 찾을 수 없습니다.")
+        return
+    }
+    ev := newAudit(c, "require-2fa", email)
+    u.Require2FA = c.PostForm("require") == "on"
+    ev.Detail = fmt.Sprint(u.Require2FA)
+    err := saveAccounts()
+    ev.record(err)
+    if err != nil {
+        c.String(http.StatusInternalServerError, fmt.Sprintf("계정 저장 오류: %v", err))
+        return
+    }
+    c.String(http.StatusOK, "2단계 인증 설정이 업데이트되었습니다. <a href='/console/admin'>돌아가기</a>")
+}
+
+// 사용자의 2단계 인증 초기화 (OTP 기기를 잃어버린 경우, 어드민)
+func reset2FA(c *gin.Context) {
+    email := c.PostForm("email")
+    u, ok := users[email]
+    if !ok {
+        c.String(http.StatusBadRequest, "사용자를 찾을 수 없습니다.")
+        return
+    }
+    ev := newAudit(c, "2fa", email)
+    ev.Detail = "reset"
+    u.TOTPSecret, u.RecoveryCodes = "", nil
+    err := saveAccounts()
+    ev.record(err)
+    if err != nil {
+        c.String(http.StatusInternalServerError, fmt.Sprintf("계정 저장 오류: %v", err))
+        return
+    }
+    c.String(http.StatusOK, "2단계 인증을 초기화했습니다. 다음 로그인 때 다시 등록해야 합니다. <a href='/console/admin'>돌아가기</a>")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+    "testing"
+    "time"
+)
+
+// RFC 4226/6238 시험용 키 "12345678901234567890" 의 base32
+const rfcTestSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+    // RFC 6238 부록 B (SHA-1). RFC 값은 8자리이므로 6자리 코드는 끝 6자리와 같아야 한다.
+    tests := []struct {
+        unix int64
+        want string
+    }{
+        {59, "94287082"},
+        {1111111109, "07081804"},
+        {1111111111, "14050471"},
+        {1234567890, "89005924"},
+        {2000000000, "69279037"},
+        {20000000000, "65353130"},
+    }
+    for _, tt := range tests {
+        got, err := totpCode(rfcTestSecret, tt.unix/totpPeriod)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if want := tt.want[len(tt.want)-totpDigits:]; got != want {
+            t.Errorf("T=%d: totpCode = %s, want %s", tt.unix, got, want)
+        }
+    }
+}
+
+func TestTOTPCodeRFC4226(t *testing.T) {
+    // RFC 4226 부록 D (카운터 0~9)
+    want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+    for counter, w := range want {
+        got, err := totpCode(rfcTestSecret, int64(counter))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got != w {
+            t.Errorf("counter=%d: totpCode = %s, want %s", counter, got, w)
+        }
+    }
+    // 소문자로 입력한 비밀키도 같은 코드
+    if got, _ := totpCode("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 1); got != want[1] {
+        t.Errorf("소문자 비밀키: totpCode = %s, want %s", got, want[1])
+    }
+    if _, err := totpCode("not base32!", 0); err == nil {
+        t.Error("잘못된 비밀키는 오류여야 함")
+    }
+}
+
+func TestVerifyTOTP(t *testing.T) {
+    secret := newTOTPSecret()
+    email := "verify-totp@test"
+    // 검사 도중 시간 단계가 바뀌지 않도록 경계 직전이면 다음 단계까지 기다린다
+    if left := totpPeriod - time.Now().Unix()%totpPeriod; left < 2 {
+        time.Sleep(time.Duration(left) * time.Second)
+    }
+    now := time.Now().Unix() / totpPeriod
+    code := func(step int64) string {
+        c, err := totpCode(secret, step)
+        if err != nil {
+            t.Fatal(err)
+        }
+        return c
+    }
+
+    if verifyTOTP(email, secret, "12345") || verifyTOTP(email, secret, "") {
+        t.Fatal("자릿수가 다른 코드는 거부해야 함")
+    }
+    if verifyTOTP(email, secret, code(now+totpSkew+1)) {
+        t.Fatal("허용 범위 밖의 코드는 거부해야 함")
+    }
+    // 이전 단계 코드는 시계 오차 범위 안이므로 허용 (공백이 섞여도 됨)
+    prev := code(now - 1)
+    if !verifyTOTP(email, secret, prev[:3]+" "+prev[3:]) {
+        t.Fatal("이전 단계 코드를 허용해야 함")
+    }
+    if !verifyTOTP(email, secret, code(now)) {
+        t.Fatal("현재 코드를 허용해야 함")
+    }
+    // 재사용과 이미 사용한 단계 이전의 코드는 거부
+    if verifyTOTP(email, secret, code(now)) {
+        t.Fatal("같은 코드 재사용은 거부해야 함")
+    }
+    if verifyTOTP(email, secret, prev) {
+        t.Fatal("사용한 단계 이전의 코드는 거부해야 함")
+    }
+    // 다른 사용자의 사용 기록과는 무관
+    if !verifyTOTP("other-"+email, secret, code(now)) {
+        t.Fatal("사용 기록은 사용자별이어야 함")
+    }
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// ------------------------------------------------------
+// 16. 저장 전 검증 (YAML 문법 + compose config)
+// ------------------------------------------------------
+
+// 검증 오류 한 건 (Line/Column 은 알 수 없으면 0)
+type validationError struct {
+    Source  string `json:"source"` // "yaml" 또는 "compose"
+    Line    int    `json:"line"`
+    Column  int    `json:"column"`
+    Message string `json:"message"`
+}
+
+var lineColPattern = regexp.MustCompile(`line (\d+)(?:,? column (\d+))?`)
+
+// newValidationError: 메시지에서 line/column 정보를 추출해 오류 생성
+func newValidationError(source, msg string) validationError {
+    ve := validationError{Source: source, Message: strings.TrimSpace(msg)}
+    if m := lineColPattern.FindStringSubmatch(msg); m != nil {
+        ve.Line, _ = strconv.Atoi(m[1])
+        if m[2] != "" {
+            ve.Column, _ = strconv.Atoi(m[2])
+        }
+    }
+    return ve
+}
+
+// isYAMLFile: 확장자로 YAML 파일 여부 판단
+func isYAMLFile(name string) bool {
+    ext := strings.ToLower(filepath.Ext(name))
+    return ext == ".yml" || ext == ".yaml"
+}
+
+// validateYAML: 내용을 YAML 로 파싱 (여러 문서 포함 가능)
+func validateYAML(content string) []validationError {
+    var errs []validationError
+    dec := yaml.NewDecoder(strings.NewReader(content))
+    for {
+        var node yaml.Node
+        err := dec.Decode(&node)
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            if te, ok := err.(*yaml.TypeError); ok {
+                for _, e := range te.Errors {
+                    errs = append(errs, newValidationError("yaml", e))
+                }
+            } else {
+                errs = append(errs, newValidationError("yaml", err.Error()))
+            }
+            break
+        }
+    }
+    return errs
+}
+
+// validateCompose: 임시 파일을 대상 디렉토리에 만들고 "compose -f <tmp> config -q" 실행
+// (상대 경로의 env_file, build context 등이 실제 위치 기준으로 해석되도록 같은 디렉토리 사용)
+func validateCompose(fullPath, content string) []validationError {
+    if composeCommand == "" {
+        return []validationError{{Source: "compose", Message: "docker compose 명령이 감지되지 않았습니다."}}
+    }
+    dir := filepath.Dir(fullPath)
+    tmp, err := ioutil.TempFile(dir, ".validate-*"+filepath.Ext(fullPath))
+    if err != nil {
+        return []validationError{{Source: "compose", Message: fmt.Sprintf("임시 파일 생성 오류: %v", err)}}
+    }
+    defer os.Remove(tmp.Name())
+    if _, err := tmp.WriteString(content); err != nil {
+        tmp.Close()
+        return []validationError{{Source: "compose", Message: fmt.Sprintf("임시 파일 저장 오류: %v", err)}}
+    }
+    tmp.Close()
+
+    out, err := composeExec(tmp.Name(), "config", "-q").CombinedOutput()
+    if err == nil {
+        return nil
+    }
+    var errs []validationError
+    for _, line := range bytes.Split(bytes.TrimSpace(out), []byte("\n")) {
+        msg := strings.TrimSpace(string(line))
+        if msg == "" {
+            continue
+        }
+        // 임시 파일명 대신 실제 파일명으로 표시
+        msg = strings.ReplaceAll(msg, filepath.Base(tmp.Name()), filepath.Base(fullPath))
+        errs = append(errs, newValidationError("compose", msg))
+    }
+    if len(errs) == 0 {
+        errs = append(errs, validationError{Source: "compose", Message: err.Error()})
+    }
+    return errs
+}
+
+// validateContent: 파일 종류에 맞는 검증 수행
+// YAML 파일은 문법 검사, compose 파일(등록 프로젝트의 compose 파일 포함)은 문법 검사 통과 시
+// compose config 검사까지 진행
+func validateContent(fullPath, content string) []validationError {
+    name := filepath.Base(fullPath)
+    if !isYAMLFile(name) {
+        return nil
+    }
+    if errs := validateYAML(content); len(errs) > 0 {
+        return errs
+    }
+    if isComposeFile(name) || isProjectComposeFile(fullPath) {
+        return validateCompose(fullPath, content)
+    }
+    return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "runtime"
+    "testing"
+)
+
+// fakeComposeConfig: config 를 항상 실패시키는 가짜 compose 명령 (compose 검증이 실행됐는지 확인용)
+func fakeComposeConfig(t *testing.T) {
+    if runtime.GOOS == "windows" {
+        t.Skip("sh 스크립트 필요")
+    }
+    script := filepath.Join(t.TempDir(), "compose")
+    if err := ioutil.WriteFile(script, []byte(`#!/bin/sh
+# 인자: [--env-file <파일>]... -f <파일> config -q
+for a in "$@"; do
+    if [ "$a" = config ]; then
+        echo "validating: services.web additional property foo is not allowed" >&2
+        exit 1
+    fi
+done
+`), 0755); err != nil {
+        t.Fatal(err)
+    }
+    old := composeCommand
+    composeCommand = script
+    t.Cleanup(func() { composeCommand = old })
+}
+
+func TestValidateContent(t *testing.T) {
+    setupRBACTree(t)
+    fakeComposeConfig(t)
+    extDir := filepath.Dir(registry.Projects[0].ComposeFile)
+    if err := ioutil.WriteFile(filepath.Join(extDir, "settings.yml"), []byte("a: 1\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    // 등록 프로젝트 이름과 상관없이 지정된 compose 파일이면 검증
+    other := filepath.Join(filepath.Dir(extDir), "infra")
+    if err := os.MkdirAll(other, 0755); err != nil {
+        t.Fatal(err)
+    }
+    if err := ioutil.WriteFile(filepath.Join(other, "prod.yaml"), []byte("services: {}\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    registry.Projects = append(registry.Projects, &project{Name: "infra-prod", ComposeFile: filepath.Join(other, "prod.yaml")})
+
+    const valid = "services:\n  web:\n    image: nginx\n"
+    tests := []struct {
+        name   string
+        rel    string
+        source string // 첫 오류의 Source ("" 이면 오류 없음)
+    }{
+        {"baseDir 의 compose 파일", "payments/docker-compose.yml", "compose"},
+        {"등록 프로젝트의 compose 파일 (이름이 compose 형태가 아님)", "@ext/stack.yml", "compose"},
+        {"다른 이름의 등록 프로젝트", "@infra-prod/prod.yaml", "compose"},
+        {"등록 프로젝트의 다른 YAML 파일", "@ext/settings.yml", ""},
+        {"YAML 이 아닌 파일", "payments/.env", ""},
+    }
+    for _, tt := range tests {
+        fullPath, err := resolvePath(tt.rel)
+        if err != nil {
+            t.Fatal(err)
+        }
+        errs := validateContent(fullPath, valid)
+        if tt.source == "" {
+            if len(errs) > 0 {
+                t.Errorf("%s: 오류가 없어야 함: %+v", tt.name, errs)
+            }
+            continue
+        }
+        if len(errs) == 0 || errs[0].Source != tt.source {
+            t.Errorf("%s: errs = %+v, want source %s", tt.name, errs, tt.source)
+        }
+    }
+
+    // YAML 문법 오류는 compose 검증 전에 걸러진다
+    fullPath, _ := resolvePath("@ext/stack.yml")
+    if errs := validateContent(fullPath, "services:\n  web: [\n"); len(errs) == 0 || errs[0].Source != "yaml" {
+        t.Errorf("문법 오류: %+v", errs)
+    }
+    // 임시 파일은 남지 않는다
+    if leftovers, _ := filepath.Glob(filepath.Join(extDir, ".validate-*")); len(leftovers) > 0 {
+        t.Errorf("임시 파일이 남음: %v", leftovers)
+    }
+}